@@ -0,0 +1,60 @@
+package opts
+
+import (
+	"slices"
+	"strings"
+)
+
+// Allow long options to be abbreviated to any unique prefix, like
+// Getopt::Long's "auto_abbrev".  So --verb works for --verbose, unless there
+// is also --verbatim.  Exact matches always win, so --verb works for --verb
+// even with --verbose around.  Negated forms from NegatableOption() are only
+// matched by abbreviations which go past the "no", so --no is not an
+// abbreviation for --noverbose.
+func (oc *Opts) AutoAbbrev() *Opts {
+	oc.autoAbbrev = true
+	return oc
+}
+
+// Find the option for long flag name, allowing for abbreviations.  Returns
+// the full name of the flag.
+func (oc *Opts) resolve(name string) (optHandler, *optInfo, string, error) {
+	if h, info, ok := oc.lookup(name); ok {
+		return h, info, name, nil
+	}
+	if !oc.autoAbbrev {
+		return nil, nil, name, &UnknownOptionError{Name: name, Suggestions: oc.suggest(name)}
+	}
+
+	// Aliases of an option are the same option, but the negated form is
+	// not.
+	type target struct {
+		info    *optInfo
+		negated bool
+	}
+	targets := make(map[target]string)
+	var candidates []string
+	for full, info := range oc.visibleNames() {
+		if !strings.HasPrefix(full, name) {
+			continue
+		}
+		negated := !info.hasName(full)
+		if negated && len(name) <= len("no") {
+			continue
+		}
+		candidates = append(candidates, "--"+full)
+		targets[target{info, negated}] = full
+	}
+
+	if len(targets) == 1 {
+		for _, full := range targets {
+			h, info, _ := oc.lookup(full)
+			return h, info, full, nil
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil, name, &UnknownOptionError{Name: name, Suggestions: oc.suggest(name)}
+	}
+	slices.Sort(candidates)
+	return nil, nil, name, &AmbiguousOptionError{Name: name, Candidates: candidates}
+}
@@ -0,0 +1,167 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoAbbrev(t *testing.T) {
+	{
+		wantTrue := false
+		wantEleven := 7
+		args := []string{
+			"--verb",
+			"--len=11",
+			"left",
+		}
+		ret, err := NewOpts().
+			AutoAbbrev().
+			SimpleOption("verbose", &wantTrue).
+			IntOption("length", &wantEleven).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	// Without AutoAbbrev(), only exact names work.
+	{
+		stayFalse := false
+		ret, err := NewOpts().
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs([]string{"--verb"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "verb not recognized")
+		}
+		assert.False(t, stayFalse)
+		assert.Equal(t, []string{"--verb"}, ret)
+	}
+
+	{
+		stayFalse := false
+		stayCalm := "calm"
+		ret, err := NewOpts().
+			AutoAbbrev().
+			SimpleOption("verbose", &stayFalse).
+			StringOption("version", &stayCalm).
+			ProcessArgs([]string{"--ver", "left"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "ambiguous, could be --verbose, --version")
+		}
+		assert.False(t, stayFalse)
+		assert.Equal(t, []string{"--ver", "left"}, ret)
+	}
+
+	// An exact match wins over longer names.
+	{
+		wantTrue := false
+		stayFalse := false
+		_, err := NewOpts().
+			AutoAbbrev().
+			SimpleOption("verb", &wantTrue).
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs([]string{"--verb"})
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.False(t, stayFalse)
+		}
+	}
+
+	// Aliases are the same option.
+	{
+		wantEleven := 7
+		_, err := NewOpts().
+			AutoAbbrev().
+			IntOption("length", &wantEleven).AKA("lengthiness").
+			ProcessArgs([]string{"--le", "11"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, wantEleven)
+		}
+	}
+
+	{
+		wantEleven := 7
+		_, err := NewOpts().
+			AutoAbbrev().
+			IntOption("length", &wantEleven).
+			ProcessArgs([]string{"--len"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "length missing")
+		}
+		assert.Equal(t, 7, wantEleven)
+	}
+}
+
+func TestAutoAbbrevNegatable(t *testing.T) {
+	{
+		wantFalse := true
+		wantTrue := false
+		_, err := NewOpts().
+			AutoAbbrev().
+			NegatableOption("verbose", &wantFalse).
+			NegatableOption("color", &wantTrue).
+			ProcessArgs([]string{"--noverb", "--col"})
+		if assert.Nil(t, err) {
+			assert.False(t, wantFalse)
+			assert.True(t, wantTrue)
+		}
+	}
+
+	// The positive and negated forms are different options.
+	{
+		stayFalse := false
+		_, err := NewOpts().
+			AutoAbbrev().
+			NegatableOption("nonsense", &stayFalse).
+			ProcessArgs([]string{"--non"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "ambiguous, could be --nononsense, --nonsense")
+		}
+	}
+
+	// Too short to reach past the "no" of the negated form.
+	{
+		stayTrue := true
+		_, err := NewOpts().
+			AutoAbbrev().
+			NegatableOption("verbose", &stayTrue).
+			ProcessArgs([]string{"--no"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no not recognized")
+		}
+		assert.True(t, stayTrue)
+	}
+
+	{
+		stayTrue := true
+		wantTrue := false
+		_, err := NewOpts().
+			AutoAbbrev().
+			NegatableOption("verbose", &stayTrue).
+			SimpleOption("notify", &wantTrue).
+			ProcessArgs([]string{"--no"})
+		if assert.Nil(t, err) {
+			assert.True(t, stayTrue)
+			assert.True(t, wantTrue)
+		}
+	}
+}
+
+func TestAutoAbbrevPersistent(t *testing.T) {
+	wantTrue := false
+	wantForce := false
+	_, err := NewOpts().
+		SimpleOption("verbose", &wantTrue).Persistent().
+		Command("deploy", NewOpts().
+			AutoAbbrev().
+			SimpleOption("force", &wantForce),
+			nil).
+		ProcessArgs([]string{"deploy", "--verb", "--fo"})
+	if assert.Nil(t, err) {
+		assert.True(t, wantTrue)
+		assert.True(t, wantForce)
+	}
+}
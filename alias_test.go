@@ -0,0 +1,154 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAKA(t *testing.T) {
+	{
+		wantEleven := 7
+		args := []string{
+			"--len", "11",
+			"left",
+		}
+		ret, err := NewOpts().
+			IntOption("length", &wantEleven).AKA("len").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantThree := 0
+		args := []string{
+			"--verbose",
+			"--chatty",
+			"-v",
+			"left",
+		}
+		ret, err := NewOpts().
+			CountingOption("verbose", &wantThree).AKA("chatty").Short("v").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 3, wantThree)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantFalse := true
+		wantTrue := false
+		args := []string{
+			"--noquiet",
+			"--loud",
+			"left",
+		}
+		ret, err := NewOpts().
+			NegatableOption("want-false", &wantFalse).AKA("quiet").
+			NegatableOption("want-true", &wantTrue).AKA("loud").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.False(t, wantFalse)
+			assert.True(t, wantTrue)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantEleven := 7
+		_, err := NewOpts().
+			AKA("len").
+			IntOption("length", &wantEleven).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no option")
+		}
+	}
+}
+
+func TestAlias(t *testing.T) {
+	{
+		wantEleven := 7
+		wantTrue := false
+		args := []string{
+			"--len=11",
+			"--sure",
+			"left",
+		}
+		ret, err := NewOpts().
+			IntOption("length", &wantEleven).
+			SimpleOption("want-true", &wantTrue).
+			Alias("length", "len", "l").
+			Alias("want-true", "sure").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, wantEleven)
+			assert.True(t, wantTrue)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantEleven := 7
+		_, err := NewOpts().
+			Alias("length", "len").
+			IntOption("length", &wantEleven).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "does not exist")
+		}
+	}
+
+	{
+		wantTrue := false
+		_, err := NewOpts().
+			NegatableOption("want-true", &wantTrue).
+			Alias("nowant-true", "sure").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "negated")
+		}
+	}
+}
+
+func TestAliasDuplicateName(t *testing.T) {
+	{
+		wantEleven := 7
+		otherEleven := 7
+		_, err := NewOpts().
+			IntOption("length", &wantEleven).
+			IntOption("other", &otherEleven).AKA("length").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "already exists")
+		}
+	}
+
+	// The negated form of an alias can also collide.
+	{
+		wantTrue := false
+		otherTrue := false
+		_, err := NewOpts().
+			SimpleOption("nosure", &otherTrue).
+			NegatableOption("want-true", &wantTrue).AKA("sure").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "already exists")
+		}
+	}
+}
+
+func TestAliasDuplicateTarget(t *testing.T) {
+	wantEleven := 7
+	_, err := NewOpts().
+		IntOption("length", &wantEleven).AKA("len").
+		IntOption("other", &wantEleven).
+		ProcessArgs([]string{})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "same pointer")
+	}
+}
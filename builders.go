@@ -1,5 +1,10 @@
 package opts
 
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
 // Add simple flag, --<name> will set *option to true.
 func (oc *Opts) SimpleOption(name string, option *bool) *Opts {
 	return oc.addOption(name, optBaseHandler[bool]{
@@ -20,7 +25,7 @@ func (oc *Opts) NegatableOption(name string, option *bool) *Opts {
 		t:      optNoArg,
 		option: option,
 		def:    true,
-	}).addOption(negatedName(name), optBaseHandler[bool]{
+	}).addNegation(name, optBaseHandler[bool]{
 		t:      optNoArg,
 		option: option,
 		def:    false,
@@ -123,26 +128,166 @@ func (oc *Opts) StringArrayOption(name string, option *[]string) *Opts {
 	})
 }
 
-// TODO:
-// func (oc *Opts) CustomOption(name string, func(...)) *Opts
-// func (oc *Opts) OptionalCustomOption(name string, func(...)) *Opts
+// Add short flag -<ch> for the preceding option, like:
+//
+//	IntOption("length", &length).Short("l")
 //
-// This would allow a caller-defined option processing.  Maybe it's better
-// to just go with a stronger package in that case?  I would be nervous
-// about how to consume multiple arguments in a clean way.
+// Short flags can be bundled, so -vvx is the same as -v -v -x.  The first
+// short flag in a bundle which takes a value uses the rest of the bundle as
+// the value, so -l24 is the same as -l 24.
+func (oc *Opts) Short(ch string) *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("short option %s has no option to follow", ch))
+		return oc
+	}
+	if utf8.RuneCountInString(ch) != 1 || ch == "-" || ch == "=" {
+		oc.setError(fmt.Errorf("short option %q must be a single character", ch))
+		return oc
+	}
+	if _, ok := oc.shorts[ch]; ok {
+		oc.setError(&DuplicateOptionError{"-" + ch})
+		return oc
+	}
+	oc.shorts[ch] = oc.last.name
+	oc.last.shorts = append(oc.last.shorts, ch)
+	return oc
+}
+
+// Add help text for the preceding option, for Usage().
+func (oc *Opts) Help(text string) *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("help %q has no option to follow", text))
+		return oc
+	}
+	oc.last.help = text
+	return oc
+}
+
+// Set the name used for the preceding option's value in Usage(), like
+// --length=LEN.  By default the name comes from the option's type.
+func (oc *Opts) Metavar(name string) *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("metavar %s has no option to follow", name))
+		return oc
+	}
+	oc.last.metavar = name
+	return oc
+}
+
+// Add string map option, every --<name>=key=val or --<name> key=val will set
+// (*option)[key] to val.  *option is created if it is nil.
+func (oc *Opts) StringMapOption(name string, option *map[string]string) *Opts {
+	return oc.addOption(name, optBaseMapHandler[string]{
+		t:      optRequiredArg,
+		option: option,
+	})
+}
 
-// TODO:
-// func (oc *Opts) AKA(name string) *Opts
-// func (oc *Opts) Short(ch string) *Opts
+// Add integer map option, every --<name>=key=val or --<name> key=val will
+// set (*option)[key] to val.  *option is created if it is nil.
+func (oc *Opts) IntMapOption(name string, option *map[string]int) *Opts {
+	return oc.addOption(name, optBaseMapHandler[int]{
+		t:      optRequiredArg,
+		option: option,
+	})
+}
+
+// Add float map option, every --<name>=key=val or --<name> key=val will set
+// (*option)[key] to val.  *option is created if it is nil.
+func (oc *Opts) FloatMapOption(name string, option *map[string]float64) *Opts {
+	return oc.addOption(name, optBaseMapHandler[float64]{
+		t:      optRequiredArg,
+		option: option,
+	})
+}
+
+// CustomValue is an option target with a caller-provided parser, built by
+// [Custom], [OptionalCustom] or [CustomArray] for use with CustomOption().
 //
-// Used like:
-//     IntOption("length", &length).AKA("len").Short("l").
-// This would key off the previous option.
+// Go methods cannot have type parameters, so this carries the type-specific
+// bits through the builder chain.
+type CustomValue struct {
+	handler optHandler
+}
+
+// Custom parses the value of an option with parse, and sets *option to the
+// result.  Like IntOption(), the value is required.
+func Custom[T any](option *T, parse func(string) (T, error)) CustomValue {
+	return CustomValue{optCustomHandler[T]{
+		t:      optRequiredArg,
+		option: option,
+		parse:  parse,
+	}}
+}
+
+// OptionalCustom is like [Custom], but with an optional value.  If the value
+// is not provided, *option is set to def.
+func OptionalCustom[T any](option *T, def T, parse func(string) (T, error)) CustomValue {
+	return CustomValue{optCustomHandler[T]{
+		t:      optOptionalArg,
+		option: option,
+		def:    def,
+		parse:  parse,
+	}}
+}
+
+// CustomArray is like [Custom], but every value is appended to *option.
+func CustomArray[T any](option *[]T, parse func(string) (T, error)) CustomValue {
+	return CustomValue{optCustomArrayHandler[T]{
+		t:      optRequiredArg,
+		option: option,
+		parse:  parse,
+	}}
+}
+
+// Add custom option, --<name>=val or --<name> val will be parsed and stored
+// as described by value, like:
 //
-// func (oc *Opts) Alias(name string, aliases ...string) *Opts
+//	CustomOption("timeout", opts.Custom(&timeout, time.ParseDuration))
+//
+// Parse errors are returned from ProcessArgs(), in which case no options are
+// stored.
+func (oc *Opts) CustomOption(name string, value CustomValue) *Opts {
+	if value.handler == nil {
+		oc.setError(fmt.Errorf("option %s has no value", name))
+		return oc
+	}
+	return oc.addOption(name, value.handler)
+}
+
+// Add alias as another name for the preceding option, like:
 //
-// This style would only need to follow the option somewhere, and could
-// perhaps configure more bits of it.
+//	IntOption("length", &length).AKA("len")
+//
+// For negatable options, --no<alias> is also added.
+func (oc *Opts) AKA(alias string) *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("alias %s has no option to follow", alias))
+		return oc
+	}
+	return oc.addAlias(oc.last, alias)
+}
+
+// Add aliases as other names for the option called name, which must
+// already exist.  Like AKA(), but can be used anywhere after the option.
+func (oc *Opts) Alias(name string, aliases ...string) *Opts {
+	info, ok := oc.infos[name]
+	if !ok {
+		oc.setError(fmt.Errorf("option %s does not exist", name))
+		return oc
+	}
+	if !info.hasName(name) {
+		oc.setError(fmt.Errorf("option %s is negated, alias %s instead", name, info.name))
+		return oc
+	}
+	for _, alias := range aliases {
+		oc.addAlias(info, alias)
+	}
+	return oc
+}
+
+// TODO: AKA() and Short() key off the previous option.  Another option
+// would have been:
 //
 // func (oc *Opts) IntOption(name string, option *int, cfg ...any) *Opts
 //
@@ -0,0 +1,108 @@
+package opts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectErrors(t *testing.T) {
+	{
+		stayFalse := false
+		stayEleven := 11
+		stayCalm := "calm"
+		args := []string{
+			"--verbose",
+			"--lenght=12",
+			"--length", "twelve",
+			"-vxy",
+			"--name",
+		}
+		ret, err := NewOpts().
+			CollectErrors().
+			SimpleOption("verbose", &stayFalse).Short("v").
+			IntOption("length", &stayEleven).
+			StringOption("name", &stayCalm).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			joined, ok := err.(interface{ Unwrap() []error })
+			if assert.True(t, ok) {
+				errs := joined.Unwrap()
+				if assert.Len(t, errs, 5) {
+					assert.IsType(t, &UnknownOptionError{}, errs[0])
+					assert.IsType(t, &InvalidValueError{}, errs[1])
+					assert.IsType(t, &UnknownOptionError{}, errs[2])
+					assert.IsType(t, &UnknownOptionError{}, errs[3])
+					assert.IsType(t, &MissingArgumentError{}, errs[4])
+				}
+			}
+			assert.Contains(t, err.Error(), "arg lenght not recognized")
+			assert.Contains(t, err.Error(), "invalid syntax")
+			assert.Contains(t, err.Error(), "arg -x not recognized")
+			assert.Contains(t, err.Error(), "arg -y not recognized")
+			assert.Contains(t, err.Error(), "arg name missing required argument")
+
+			var verr *InvalidValueError
+			if assert.True(t, errors.As(err, &verr)) {
+				assert.Equal(t, "twelve", verr.Value)
+				assert.Equal(t, 2, verr.Index)
+			}
+		}
+		assert.False(t, stayFalse)
+		assert.Equal(t, 11, stayEleven)
+		assert.Equal(t, "calm", stayCalm)
+		assert.Equal(t, args, ret)
+	}
+
+	// A single error is not wrapped.
+	{
+		stayFalse := false
+		_, err := NewOpts().
+			CollectErrors().
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs([]string{"--verbose", "--verbsoe"})
+		if assert.NotNil(t, err) {
+			assert.IsType(t, &UnknownOptionError{}, err)
+		}
+		assert.False(t, stayFalse)
+	}
+
+	// Subcommands collect errors too, with the parent's.
+	{
+		stayFalse := false
+		stayForce := false
+		_, err := NewOpts().
+			CollectErrors().
+			SimpleOption("verbose", &stayFalse).
+			Command("deploy", NewOpts().SimpleOption("force", &stayForce), nil).
+			ProcessArgs([]string{"--verbose", "--quiet", "deploy", "--force", "--dry-run", "--yes"})
+		if assert.NotNil(t, err) {
+			var errs []error
+			if joined, ok := err.(interface{ Unwrap() []error }); assert.True(t, ok) {
+				errs = joined.Unwrap()
+			}
+			var names []string
+			for _, err := range errs {
+				var uerr *UnknownOptionError
+				if assert.True(t, errors.As(err, &uerr)) {
+					names = append(names, uerr.Name)
+				}
+			}
+			assert.Equal(t, []string{"quiet", "dry-run", "yes"}, names)
+		}
+		assert.False(t, stayFalse)
+		assert.False(t, stayForce)
+	}
+
+	// Without CollectErrors(), the first error stops processing.
+	{
+		stayFalse := false
+		_, err := NewOpts().
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs([]string{"--quiet", "--loud"})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "arg quiet not recognized", err.Error())
+		}
+	}
+}
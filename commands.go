@@ -0,0 +1,169 @@
+package opts
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// A subcommand added by Command().
+type optCommand struct {
+	opts *Opts
+	run  func(args []string) error
+}
+
+// CommandError is returned by ProcessArgs() when subcommands have been
+// added with Command(), but the arguments do not select one.
+type CommandError struct {
+	// The argument which did not match a subcommand, or "" if there
+	// were no arguments left.
+	Name string
+
+	// The valid subcommands, sorted.
+	Commands []string
+}
+
+func (e *CommandError) Error() string {
+	valid := strings.Join(e.Commands, ", ")
+	if e.Name == "" {
+		return fmt.Sprintf("missing command, expected one of %s", valid)
+	}
+	return fmt.Sprintf("command %s not recognized, expected one of %s", e.Name, valid)
+}
+
+// Add subcommand name, with its own options in sub, like:
+//
+//	NewOpts().
+//	    SimpleOption("verbose", &verbose).Persistent().
+//	    Command("deploy", NewOpts().SimpleOption("force", &force), deploy).
+//	    Command("status", NewOpts().SimpleOption("json", &json), status).
+//	    Run(args)
+//
+// Options before the subcommand name are processed by oc, options after it
+// by sub.  Options marked with Persistent() are valid in both places.  Run()
+// calls run with the arguments left after the subcommand's options.
+//
+// sub can have subcommands of its own.
+func (oc *Opts) Command(name string, sub *Opts, run func(args []string) error) *Opts {
+	if _, ok := oc.commands[name]; ok {
+		oc.setError(fmt.Errorf("command %s already exists", name))
+		return oc
+	}
+	if sub.parent != nil {
+		oc.setError(fmt.Errorf("command %s already added elsewhere", name))
+		return oc
+	}
+	sub.parent = oc
+	oc.commands[name] = &optCommand{sub, run}
+	return oc
+}
+
+// Make the preceding option valid after subcommand names, as well as before.
+func (oc *Opts) Persistent() *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("persistent has no option to follow"))
+		return oc
+	}
+	oc.last.persistent = true
+	return oc
+}
+
+// Returns the name of the subcommand selected by the last ProcessArgs(), or
+// "" if there was none.
+func (oc *Opts) SelectedCommand() string {
+	return oc.selected
+}
+
+// Find the option for flag name, including persistent options from parents.
+func (oc *Opts) lookup(name string) (optHandler, *optInfo, bool) {
+	if h, ok := oc.handlers[name]; ok {
+		return h, oc.infos[name], true
+	}
+	for p := oc.parent; p != nil; p = p.parent {
+		if h, ok := p.handlers[name]; ok && p.infos[name].persistent {
+			return h, p.infos[name], true
+		}
+	}
+	return nil, nil, false
+}
+
+// Like lookup(), for short flag ch.
+func (oc *Opts) lookupShort(ch string) (optHandler, *optInfo, bool) {
+	if name, ok := oc.shorts[ch]; ok {
+		return oc.handlers[name], oc.infos[name], true
+	}
+	for p := oc.parent; p != nil; p = p.parent {
+		if name, ok := p.shorts[ch]; ok && p.infos[name].persistent {
+			return p.handlers[name], p.infos[name], true
+		}
+	}
+	return nil, nil, false
+}
+
+// Returns true if lookupShort() can find any short flags.
+func (oc *Opts) hasShorts() bool {
+	if len(oc.shorts) > 0 {
+		return true
+	}
+	for p := oc.parent; p != nil; p = p.parent {
+		for _, name := range p.shorts {
+			if p.infos[name].persistent {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns every flag name lookup() can find.
+func (oc *Opts) visibleNames() map[string]*optInfo {
+	names := maps.Clone(oc.infos)
+	for p := oc.parent; p != nil; p = p.parent {
+		for name, info := range p.infos {
+			if _, ok := names[name]; !ok && info.persistent {
+				names[name] = info
+			}
+		}
+	}
+	return names
+}
+
+// Returns the subcommand named by the first of args.
+func (oc *Opts) selectCommand(args []string) (*optCommand, error) {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+		if cmd, ok := oc.commands[name]; ok {
+			return cmd, nil
+		}
+	}
+
+	return nil, &CommandError{Name: name, Commands: oc.commandNames()}
+}
+
+// Process args like ProcessArgs(), then call the run function of the selected
+// subcommand with the remaining args.  For nested subcommands, the innermost
+// subcommand is run.
+func (oc *Opts) Run(args []string) error {
+	if len(oc.commands) == 0 {
+		return fmt.Errorf("no commands to run")
+	}
+
+	r, err := oc.process(args)
+	if err != nil {
+		return err
+	}
+	r.Commit()
+	oc.store(r)
+	return r.run()
+}
+
+// Wrapper to pass [os.Args][1:] to [Run].  Handles [CompletionArg] like
+// ProcessOSArgs().
+func (oc *Opts) RunOSArgs() error {
+	if oc.completeOSArgs() {
+		return nil
+	}
+	return oc.Run(os.Args[1:])
+}
@@ -0,0 +1,200 @@
+package opts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand(t *testing.T) {
+	{
+		wantTrue := false
+		wantForce := false
+		stayJSON := false
+		var gotArgs []string
+		ran := ""
+		oc := NewOpts().
+			SimpleOption("verbose", &wantTrue).
+			Command("deploy", NewOpts().
+				SimpleOption("force", &wantForce),
+				func(args []string) error {
+					ran = "deploy"
+					gotArgs = args
+					return nil
+				}).
+			Command("status", NewOpts().
+				SimpleOption("json", &stayJSON),
+				func(args []string) error {
+					ran = "status"
+					return nil
+				})
+		err := oc.Run([]string{
+			"--verbose",
+			"deploy",
+			"--force",
+			"left",
+		})
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.True(t, wantForce)
+			assert.False(t, stayJSON)
+			assert.Equal(t, "deploy", ran)
+			assert.Equal(t, "deploy", oc.SelectedCommand())
+			assert.Equal(t, []string{"left"}, gotArgs)
+		}
+	}
+
+	// Options are only valid on their own side of the subcommand.
+	{
+		stayFalse := false
+		stayForce := false
+		oc := NewOpts().
+			SimpleOption("verbose", &stayFalse).
+			Command("deploy", NewOpts().SimpleOption("force", &stayForce), nil)
+		ret, err := oc.ProcessArgs([]string{"deploy", "--verbose"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "verbose not recognized")
+		}
+		assert.Equal(t, []string{"deploy", "--verbose"}, ret)
+
+		ret, err = oc.ProcessArgs([]string{"--force", "deploy"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "force not recognized")
+		}
+		assert.Equal(t, []string{"--force", "deploy"}, ret)
+		assert.False(t, stayFalse)
+		assert.False(t, stayForce)
+	}
+
+	// Errors from the subcommand leave the parent's options untouched.
+	{
+		stayFalse := false
+		stayEleven := 11
+		ret, err := NewOpts().
+			SimpleOption("verbose", &stayFalse).
+			Command("deploy", NewOpts().IntOption("count", &stayEleven), nil).
+			ProcessArgs([]string{"--verbose", "deploy", "--count=many"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "invalid syntax")
+		}
+		assert.False(t, stayFalse)
+		assert.Equal(t, 11, stayEleven)
+		assert.Equal(t, []string{"--verbose", "deploy", "--count=many"}, ret)
+	}
+
+	{
+		wantTrue := false
+		err := NewOpts().
+			Command("outer", NewOpts().
+				Command("inner", NewOpts().SimpleOption("want-true", &wantTrue), nil),
+				nil).
+			Run([]string{"outer", "inner", "--want-true"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "inner cannot be run")
+		}
+		assert.True(t, wantTrue)
+	}
+
+	{
+		err := NewOpts().
+			Run([]string{"deploy"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no commands")
+		}
+	}
+}
+
+func TestNestedCommand(t *testing.T) {
+	wantTrue := false
+	wantEleven := 7
+	var gotArgs []string
+	err := NewOpts().
+		SimpleOption("verbose", &wantTrue).Persistent().
+		Command("remote", NewOpts().
+			Command("add", NewOpts().IntOption("count", &wantEleven),
+				func(args []string) error {
+					gotArgs = args
+					return nil
+				}),
+			nil).
+		Run([]string{"remote", "add", "--count", "11", "--verbose", "origin"})
+	if assert.Nil(t, err) {
+		assert.True(t, wantTrue)
+		assert.Equal(t, 11, wantEleven)
+		assert.Equal(t, []string{"origin"}, gotArgs)
+	}
+}
+
+func TestPersistent(t *testing.T) {
+	{
+		wantThree := 0
+		wantTrue := false
+		ret, err := NewOpts().
+			CountingOption("verbose", &wantThree).Short("v").Persistent().
+			Command("deploy", NewOpts().SimpleOption("force", &wantTrue).Short("f"), nil).
+			ProcessArgs([]string{"-v", "deploy", "--verbose", "-vf", "left"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 3, wantThree)
+			assert.True(t, wantTrue)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	// The persistent option was seen after the subcommand, so the
+	// environment is not used.
+	{
+		t.Setenv("TEST_OPTS_NAMES", "seven,eleven")
+		wantOne := []string{}
+		_, err := NewOpts().
+			StringArrayOption("names", &wantOne).Env("TEST_OPTS_NAMES").Persistent().
+			Command("deploy", NewOpts(), nil).
+			ProcessArgs([]string{"deploy", "--names", "chaos"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"chaos"}, wantOne)
+		}
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	oc := NewOpts().
+		Command("status", NewOpts(), nil).
+		Command("deploy", NewOpts(), nil)
+
+	{
+		ret, err := oc.ProcessArgs([]string{"launch", "left"})
+		var cmdErr *CommandError
+		require.True(t, errors.As(err, &cmdErr))
+		assert.Equal(t, "launch", cmdErr.Name)
+		assert.Equal(t, []string{"deploy", "status"}, cmdErr.Commands)
+		assert.Contains(t, err.Error(), "launch not recognized")
+		assert.Equal(t, []string{"launch", "left"}, ret)
+	}
+
+	{
+		_, err := oc.ProcessArgs([]string{})
+		var cmdErr *CommandError
+		require.True(t, errors.As(err, &cmdErr))
+		assert.Equal(t, "", cmdErr.Name)
+		assert.Contains(t, err.Error(), "missing command")
+	}
+
+	{
+		sub := NewOpts()
+		_, err := NewOpts().
+			Command("deploy", sub, nil).
+			Command("deploy", NewOpts(), nil).
+			ProcessArgs([]string{"deploy"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "already exists")
+		}
+
+		_, err = NewOpts().
+			Command("other", sub, nil).
+			ProcessArgs([]string{"other"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "elsewhere")
+		}
+	}
+}
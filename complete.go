@@ -0,0 +1,196 @@
+package opts
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// CompletionArg is the hidden first argument which makes ProcessOSArgs() and
+// RunOSArgs() write completions rather than processing options.  Shells can
+// run "prog __complete <words...>" to complete the last word.
+const CompletionArg = "__complete"
+
+// Replaced by tests.
+var osExit = os.Exit
+
+// Complete the preceding option's value by calling fn with the text typed so
+// far.  Candidates which do not start with prefix are dropped.  Takes
+// precedence over CompleteChoices().
+func (oc *Opts) CompleteFunc(fn func(prefix string) []string) *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("completion func has no option to follow"))
+		return oc
+	}
+	oc.last.completer = fn
+	return oc
+}
+
+// Returns the option's value candidates starting with prefix.
+func (info *optInfo) completeValue(prefix string) []string {
+	candidates := info.choices
+	if info.completer != nil {
+		candidates = info.completer(prefix)
+	}
+	return filterPrefix(candidates, prefix)
+}
+
+// Returns the entries of candidates which start with prefix.
+func filterPrefix(candidates []string, prefix string) []string {
+	var ret []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// Returns every flag which can be used with oc, sorted.
+func (oc *Opts) completionFlags() []string {
+	var flags []string
+	for name := range oc.visibleNames() {
+		flags = append(flags, "--"+name)
+	}
+	for o := oc; o != nil; o = o.parent {
+		for ch, name := range o.shorts {
+			if o == oc || o.infos[name].persistent {
+				flags = append(flags, "-"+ch)
+			}
+		}
+	}
+	slices.Sort(flags)
+	return slices.Compact(flags)
+}
+
+// Returns the completions for cur, which follows words.  words are expanded
+// and walked the same way ProcessArgs() would, to find subcommands and
+// whether cur is an option or the value for one.  Unknown options are
+// skipped.
+func (oc *Opts) completions(words []string, cur string) []string {
+	// Response files which can't be read are left as they are.
+	if expanded, err := oc.expandArgs(words); err == nil {
+		words = expanded
+	}
+
+	o := oc
+	var pending *optInfo
+	for len(words) > 0 {
+		word := words[0]
+		words = words[1:]
+
+		if word == "--" {
+			return nil
+		}
+
+		if name, inline, ok := splitLong(word); ok {
+			h, info, _, err := o.resolve(name)
+			if err != nil {
+				continue
+			}
+			var atCursor bool
+			_, words, atCursor = o.optionArg(h, inline, words)
+			if atCursor {
+				pending = info
+			}
+			continue
+		}
+
+		if bundle, ok := strings.CutPrefix(word, "-"); ok && len(bundle) > 0 && o.hasShorts() {
+			chars := []rune(bundle)
+			for i, ch := range chars {
+				h, info, ok := o.lookupShort(string(ch))
+				if !ok {
+					break
+				}
+				if h.getType() == optNoArg {
+					continue
+				}
+				var inline []string
+				if i+1 < len(chars) {
+					inline = []string{string(chars[i+1:])}
+				}
+				var atCursor bool
+				_, words, atCursor = o.optionArg(h, inline, words)
+				if atCursor {
+					pending = info
+				}
+				break
+			}
+			continue
+		}
+
+		if len(o.commands) > 0 {
+			cmd, ok := o.commands[word]
+			if !ok {
+				return nil
+			}
+			o = cmd.opts
+		} else if !o.permute {
+			return nil
+		}
+	}
+
+	if pending != nil {
+		if pending.handler.getType() == optRequiredArg || !o.looksLikeOption(cur) {
+			return pending.completeValue(cur)
+		}
+	}
+
+	if name, inline, ok := splitLong(cur); ok && len(inline) > 0 {
+		h, info, _, err := o.resolve(name)
+		if err != nil || h.getType() == optNoArg {
+			return nil
+		}
+		var ret []string
+		for _, v := range info.completeValue(inline[0]) {
+			ret = append(ret, "--"+name+"="+v)
+		}
+		return ret
+	}
+	if strings.HasPrefix(cur, "-") {
+		return filterPrefix(o.completionFlags(), cur)
+	}
+	return filterPrefix(o.commandNames(), cur)
+}
+
+// Write completions for the last of args to w, one per line.  args are the
+// arguments as they would be passed to ProcessArgs(), with the last being
+// the partial word to complete, which may be "".  The word is completed as
+// an option name, as a value using CompleteFunc() or CompleteChoices(), or as
+// a subcommand name, depending on where it falls.
+func (oc *Opts) WriteCompletions(w io.Writer, args []string) error {
+	if oc.err != nil {
+		return oc.err
+	}
+
+	cur := ""
+	if len(args) > 0 {
+		cur = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	var b strings.Builder
+	for _, c := range oc.completions(args, cur) {
+		fmt.Fprintln(&b, c)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// If [os.Args][1] is CompletionArg, write completions for the rest of
+// [os.Args] and exit.  Returns true if completions were written, which is
+// only seen when tests replace osExit.
+func (oc *Opts) completeOSArgs() bool {
+	if len(os.Args) < 2 || os.Args[1] != CompletionArg {
+		return false
+	}
+	if err := oc.WriteCompletions(os.Stdout, os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		osExit(1)
+		return true
+	}
+	osExit(0)
+	return true
+}
@@ -0,0 +1,149 @@
+package opts
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Options for exercising completions.
+func completeOpts() *Opts {
+	var (
+		verbose  = false
+		length   = 24
+		format   = "table"
+		cluster  = "dev"
+		level    = 0
+		force    = false
+		replicas = 1
+	)
+	clusters := func(prefix string) []string {
+		return []string{"dev", "prod-east", "prod-west"}
+	}
+	return NewOpts().
+		NegatableOption("verbose", &verbose).Short("v").Persistent().
+		IntOption("length", &length).Short("l").
+		StringOption("format", &format).CompleteChoices("json", "table", "yaml").
+		StringOption("cluster", &cluster).Short("c").CompleteFunc(clusters).Persistent().
+		OptionalIntOption("level", &level, 3).CompleteChoices("1", "2", "3").
+		Command("deploy", NewOpts().
+			SimpleOption("force", &force).Short("f").
+			IntOption("replicas", &replicas).CompleteChoices("1", "3", "5"),
+			nil).
+		Command("status", NewOpts(), nil)
+}
+
+func completeLines(t *testing.T, oc *Opts, args ...string) []string {
+	t.Helper()
+	var b strings.Builder
+	require.Nil(t, oc.WriteCompletions(&b, args))
+	if b.Len() == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+func TestCompleteOptions(t *testing.T) {
+	oc := completeOpts()
+	assert.Equal(t, []string{"--format"}, completeLines(t, oc, "--fo"))
+	assert.Equal(t, []string{"--noverbose"}, completeLines(t, oc, "--nov"))
+	assert.Equal(t,
+		[]string{"--cluster", "--format", "--length", "--level", "--noverbose", "--verbose", "-c", "-l", "-v"},
+		completeLines(t, oc, "-"))
+
+	// After the subcommand, its own options and persistent ones.
+	assert.Equal(t,
+		[]string{"--cluster", "--force", "--noverbose", "--replicas", "--verbose", "-c", "-f", "-v"},
+		completeLines(t, oc, "deploy", "-"))
+	assert.Nil(t, completeLines(t, oc, "status", "--f"))
+}
+
+func TestCompleteValues(t *testing.T) {
+	oc := completeOpts()
+	assert.Equal(t, []string{"json"}, completeLines(t, oc, "--format", "j"))
+	assert.Equal(t, []string{"json", "table", "yaml"}, completeLines(t, oc, "--format", ""))
+	assert.Equal(t, []string{"--format=table"}, completeLines(t, oc, "--format=t"))
+	assert.Equal(t, []string{"prod-east", "prod-west"}, completeLines(t, oc, "-c", "prod"))
+	assert.Equal(t, []string{"prod-east", "prod-west"}, completeLines(t, oc, "-vc", "prod"))
+	assert.Equal(t, []string{"prod-east"}, completeLines(t, oc, "deploy", "--cluster", "prod-e"))
+	assert.Equal(t, []string{"1", "3", "5"}, completeLines(t, oc, "deploy", "-f", "--replicas", ""))
+
+	// A value for --length without a completer has no candidates, even
+	// though it looks like an option.
+	assert.Nil(t, completeLines(t, oc, "--length", "--"))
+
+	// Optional values only complete if they do not look like options.
+	assert.Equal(t, []string{"1", "2", "3"}, completeLines(t, oc, "--level", ""))
+	assert.Equal(t, []string{"--verbose"}, completeLines(t, oc, "--level", "--verb"))
+
+	// Values already given are skipped.
+	assert.Equal(t, []string{"deploy", "status"}, completeLines(t, oc, "--format", "json", "-l24", ""))
+}
+
+func TestCompleteResponseFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"deploy.args": {Data: []byte("--cluster prod-east deploy\n")},
+		"format.args": {Data: []byte("--format\n")},
+	}
+	oc := completeOpts().ResponseFiles(fsys)
+	assert.Equal(t, []string{"--force"}, completeLines(t, oc, "@deploy.args", "--fo"))
+	assert.Equal(t, []string{"json"}, completeLines(t, oc, "@format.args", "j"))
+
+	// Files which can't be read are just words.
+	assert.Nil(t, completeLines(t, oc, "@missing.args", "d"))
+}
+
+func TestCompleteCommands(t *testing.T) {
+	oc := completeOpts()
+	assert.Equal(t, []string{"deploy", "status"}, completeLines(t, oc, ""))
+	assert.Equal(t, []string{"deploy"}, completeLines(t, oc, "-v", "--cluster", "dev", "d"))
+	assert.Nil(t, completeLines(t, oc, "launch", ""))
+	assert.Nil(t, completeLines(t, oc, "--", "-"))
+	assert.Nil(t, completeLines(t, oc, "deploy", "d"))
+}
+
+func TestCompleteOSArgs(t *testing.T) {
+	tmpArgs := os.Args
+	tmpStdout := os.Stdout
+	tmpStderr := os.Stderr
+	defer func() {
+		os.Args = tmpArgs
+		os.Stdout = tmpStdout
+		os.Stderr = tmpStderr
+		osExit = os.Exit
+	}()
+
+	exitCode := -1
+	osExit = func(code int) {
+		exitCode = code
+	}
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	require.Nil(t, err)
+	os.Stdout = f
+	os.Stderr = f
+
+	os.Args = []string{"command", CompletionArg, "--form"}
+	require.Nil(t, completeOpts().ProcessOSArgs())
+	assert.Equal(t, 0, exitCode)
+
+	os.Args = []string{"command", CompletionArg, "deploy", "--rep"}
+	require.Nil(t, completeOpts().RunOSArgs())
+	assert.Equal(t, 0, exitCode)
+
+	out, err := os.ReadFile(f.Name())
+	require.Nil(t, err)
+	assert.Equal(t, "--format\n--replicas\n", string(out))
+
+	// Construction errors exit with an error.
+	os.Args = []string{"command", CompletionArg, "--form"}
+	require.Nil(t, NewOpts().CompleteFunc(nil).ProcessOSArgs())
+	assert.Equal(t, 1, exitCode)
+
+	out, err = os.ReadFile(f.Name())
+	require.Nil(t, err)
+	assert.Contains(t, string(out), "no option")
+}
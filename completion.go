@@ -0,0 +1,257 @@
+package opts
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Complete the preceding option's value from values, in generated completion
+// scripts.  This is only a hint, other values are still accepted.
+func (oc *Opts) CompleteChoices(values ...string) *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("completion choices have no option to follow"))
+		return oc
+	}
+	oc.last.choices = values
+	return oc
+}
+
+// Complete the preceding option's value as a file path, in generated
+// completion scripts.
+func (oc *Opts) CompleteFiles() *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("completion files have no option to follow"))
+		return oc
+	}
+	oc.last.files = true
+	return oc
+}
+
+// Returns the long names of the option, without the negated forms.
+func (info *optInfo) longNames() []string {
+	return append([]string{info.name}, info.aliases...)
+}
+
+// Returns every flag which reaches the option, like -l, --length and
+// --nolength.
+func (info *optInfo) flags() []string {
+	var flags []string
+	for _, ch := range info.shorts {
+		flags = append(flags, "-"+ch)
+	}
+	for _, name := range info.longNames() {
+		flags = append(flags, "--"+name)
+	}
+	if info.negation != nil {
+		for _, name := range info.longNames() {
+			flags = append(flags, "--"+negatedName(name))
+		}
+	}
+	return flags
+}
+
+// Returns the names of oc's subcommands, sorted.
+func (oc *Opts) commandNames() []string {
+	names := make([]string, 0, len(oc.commands))
+	for name := range oc.commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Quote text for bash.
+func bashQuote(text string) string {
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
+
+// Write a bash completion script for the program prog to w.  Source it, or
+// install it in the bash-completion directory.  Options taking a value
+// complete the following word from CompleteChoices() or CompleteFiles().
+// Subcommand names are completed, but not the subcommands' own options.
+func (oc *Opts) BashCompletion(w io.Writer, prog string) error {
+	if oc.err != nil {
+		return oc.err
+	}
+
+	fn := "_" + nonIdentifier.ReplaceAllString(prog, "_") + "_completion"
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s, generated by github.com/dshess/opts.\n", prog)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("\tlocal prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+
+	var flags []string
+	var values strings.Builder
+	for _, info := range oc.options() {
+		flags = append(flags, info.flags()...)
+		// Optional values are usually attached with =, which bash
+		// splits into separate words, so only handle required values.
+		if info.handler.getType() != optRequiredArg {
+			continue
+		}
+		fmt.Fprintf(&values, "\t%s)\n", strings.Join(info.flags(), "|"))
+		if len(info.choices) > 0 {
+			fmt.Fprintf(&values, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", bashQuote(strings.Join(info.choices, " ")))
+		} else if info.files {
+			values.WriteString("\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+		}
+		values.WriteString("\t\treturn\n\t\t;;\n")
+	}
+	if values.Len() > 0 {
+		b.WriteString("\tcase \"$prev\" in\n")
+		b.WriteString(values.String())
+		b.WriteString("\tesac\n")
+	}
+
+	b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", bashQuote(strings.Join(flags, " ")))
+	b.WriteString("\t\treturn\n\tfi\n")
+	if len(oc.commands) > 0 {
+		fmt.Fprintf(&b, "\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", bashQuote(strings.Join(oc.commandNames(), " ")))
+	}
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", fn, prog)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Escape text for the description in a zsh _arguments spec, which is in
+// single quotes.
+func zshEscape(text string) string {
+	r := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`, `:`, `\:`)
+	return r.Replace(text)
+}
+
+// Returns the zsh _arguments action for the option's value.
+func (info *optInfo) zshAction() string {
+	if len(info.choices) > 0 {
+		return "(" + strings.Join(info.choices, " ") + ")"
+	} else if info.files {
+		return "_files"
+	}
+	return ""
+}
+
+// Write a zsh completion script for the program prog to w.  Install it as
+// _<prog> somewhere in $fpath.  See BashCompletion() for what is completed.
+func (oc *Opts) ZshCompletion(w io.Writer, prog string) error {
+	if oc.err != nil {
+		return oc.err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n", prog)
+	fmt.Fprintf(&b, "# zsh completion for %s, generated by github.com/dshess/opts.\n", prog)
+	b.WriteString("_arguments -s")
+	for _, info := range oc.options() {
+		help := zshEscape(info.help)
+		repeat := ""
+		if isArray(info.handler) {
+			repeat = "*"
+		} else if _, counting := info.handler.(optCountingHandler); counting {
+			repeat = "*"
+		}
+
+		value := ""
+		switch info.handler.getType() {
+		case optOptionalArg:
+			value = fmt.Sprintf("::%s:%s", zshEscape(info.valueName()), info.zshAction())
+		case optRequiredArg:
+			value = fmt.Sprintf(":%s:%s", zshEscape(info.valueName()), info.zshAction())
+		}
+
+		for _, ch := range info.shorts {
+			sep := ""
+			if value != "" {
+				sep = "+"
+			}
+			fmt.Fprintf(&b, " \\\n\t'%s-%s%s[%s]%s'", repeat, ch, sep, help, value)
+		}
+		for _, name := range info.longNames() {
+			sep := ""
+			switch info.handler.getType() {
+			case optOptionalArg:
+				sep = "=-"
+			case optRequiredArg:
+				sep = "="
+			}
+			fmt.Fprintf(&b, " \\\n\t'%s--%s%s[%s]%s'", repeat, name, sep, help, value)
+			if info.negation != nil {
+				fmt.Fprintf(&b, " \\\n\t'%s--%s[%s]'", repeat, negatedName(name), help)
+			}
+		}
+	}
+	if len(oc.commands) > 0 {
+		fmt.Fprintf(&b, " \\\n\t'1:command:(%s)'", strings.Join(oc.commandNames(), " "))
+		b.WriteString(" \\\n\t'*::arg:_files'")
+	} else {
+		b.WriteString(" \\\n\t'*:arg:_files'")
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Quote text for fish.
+func fishQuote(text string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(text) + "'"
+}
+
+// Write a fish completion script for the program prog to w.  Install it as
+// <prog>.fish in a fish completions directory.  See BashCompletion() for what
+// is completed.
+func (oc *Opts) FishCompletion(w io.Writer, prog string) error {
+	if oc.err != nil {
+		return oc.err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s, generated by github.com/dshess/opts.\n", prog)
+	for _, info := range oc.options() {
+		line := "complete -c " + prog
+		for _, ch := range info.shorts {
+			line += " -s " + ch
+		}
+		for _, name := range info.longNames() {
+			line += " -l " + name
+		}
+		if info.handler.getType() == optRequiredArg {
+			line += " -r"
+		}
+		if len(info.choices) > 0 {
+			line += " -f -a " + fishQuote(strings.Join(info.choices, " "))
+		} else if info.files {
+			line += " -F"
+		}
+		if info.help != "" {
+			line += " -d " + fishQuote(info.help)
+		}
+		b.WriteString(line + "\n")
+
+		if info.negation != nil {
+			line := "complete -c " + prog
+			for _, name := range info.longNames() {
+				line += " -l " + negatedName(name)
+			}
+			if info.help != "" {
+				line += " -d " + fishQuote(info.help)
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	for _, name := range oc.commandNames() {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -f -a %s\n", prog, name)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
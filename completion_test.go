@@ -0,0 +1,114 @@
+package opts
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// Compare got against testdata/name, or update it with -update.
+func checkGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.Nil(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+// Options covering the interesting cases for generated scripts and docs.
+func goldenOpts() *Opts {
+	var (
+		length  = 24
+		verbose = true
+		debug   = 0
+		files   = []string{}
+		format  = "table"
+		level   = 0
+		labels  = map[string]string{}
+	)
+	return NewOpts().
+		IntOption("length", &length).Short("l").AKA("len").Help("Length of the data.").
+		NegatableOption("verbose", &verbose).Short("v").Help("Be chatty, or [not].").
+		CountingOption("debug", &debug).Short("d").Help("More debugging.").
+		StringArrayOption("file", &files).Metavar("FILE").CompleteFiles().Help("Input files.").
+		StringOption("format", &format).CompleteChoices("json", "table", "yaml").Help("Output format: one of json, table or yaml.").
+		OptionalIntOption("level", &level, 3).Help("Compression level, it's 3 if not given.").
+		StringMapOption("label", &labels).Help("Labels to attach.")
+}
+
+func TestBashCompletion(t *testing.T) {
+	var b strings.Builder
+	require.Nil(t, goldenOpts().BashCompletion(&b, "tool"))
+	checkGolden(t, "completion.bash.golden", b.String())
+}
+
+func TestZshCompletion(t *testing.T) {
+	var b strings.Builder
+	require.Nil(t, goldenOpts().ZshCompletion(&b, "tool"))
+	checkGolden(t, "completion.zsh.golden", b.String())
+}
+
+func TestFishCompletion(t *testing.T) {
+	var b strings.Builder
+	require.Nil(t, goldenOpts().FishCompletion(&b, "tool"))
+	checkGolden(t, "completion.fish.golden", b.String())
+}
+
+func TestCompletionCommands(t *testing.T) {
+	verbose := false
+	oc := NewOpts().
+		SimpleOption("verbose", &verbose).
+		Command("status", NewOpts(), nil).
+		Command("deploy", NewOpts(), nil)
+
+	{
+		var b strings.Builder
+		require.Nil(t, oc.BashCompletion(&b, "my-tool"))
+		checkGolden(t, "commands.bash.golden", b.String())
+	}
+
+	{
+		var b strings.Builder
+		require.Nil(t, oc.ZshCompletion(&b, "my-tool"))
+		checkGolden(t, "commands.zsh.golden", b.String())
+	}
+
+	{
+		var b strings.Builder
+		require.Nil(t, oc.FishCompletion(&b, "my-tool"))
+		checkGolden(t, "commands.fish.golden", b.String())
+	}
+}
+
+func TestCompletionErrors(t *testing.T) {
+	oc := NewOpts().
+		CompleteFiles()
+	var b strings.Builder
+	if err := oc.BashCompletion(&b, "tool"); assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no option")
+	}
+	if err := oc.ZshCompletion(&b, "tool"); assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no option")
+	}
+	if err := oc.FishCompletion(&b, "tool"); assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no option")
+	}
+	assert.Empty(t, b.String())
+
+	_, err := NewOpts().
+		CompleteChoices("a", "b").
+		ProcessArgs([]string{})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no option")
+	}
+}
@@ -0,0 +1,447 @@
+package opts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat is the format of a file for LoadConfig().
+type ConfigFormat int
+
+const (
+	// A JSON object.
+	ConfigJSON ConfigFormat = iota
+
+	// A YAML mapping.
+	ConfigYAML
+
+	// Lines of key = value, with [section] headers.
+	ConfigINI
+)
+
+func (f ConfigFormat) String() string {
+	switch f {
+	case ConfigJSON:
+		return "JSON"
+	case ConfigYAML:
+		return "YAML"
+	case ConfigINI:
+		return "INI"
+	default:
+		return fmt.Sprintf("ConfigFormat(%d)", int(f))
+	}
+}
+
+// The kinds of value in a config file.
+type configKind int
+
+const (
+	configScalar configKind = iota
+	configList
+	configTable
+)
+
+// A value read from a config file, before it is matched to options.
+// Scalars are kept as text, to be parsed like command-line values.
+type configNode struct {
+	kind configKind
+
+	// The value of a scalar, or the values of a list.
+	values []string
+
+	// The line of each of values in the file, if known.
+	lines []int
+
+	// The keys and values of a table, in file order.
+	fields []configField
+}
+
+type configField struct {
+	key  string
+	node configNode
+}
+
+// Load option values from the config file in r.  Keys are option names,
+// including aliases and negated forms, and values are parsed like values
+// on the command line.  Lists give several values to array options, and
+// tables give key=value pairs to map options.  A table named for a
+// subcommand holds the subcommand's options, like:
+//
+//	{"verbose": true, "file": ["a", "b"], "deploy": {"replicas": 3}}
+//
+// In INI files, [deploy] starts the subcommand's options, [deploy.start] the
+// options of deploy's start subcommand, and a repeated key gives several
+// values.
+//
+// The values are stored by the next ProcessArgs(), if it succeeds, and are
+// not used again after that.  A Parser from Compile() uses them for every
+// Parse().  The command line and the environment take precedence over
+// config files, see Sources().  Values from files loaded later take
+// precedence over files loaded earlier, except that array options get the
+// values from all of them.  If the file has an error, nothing from it is
+// used.
+func (oc *Opts) LoadConfig(r io.Reader, format ConfigFormat) error {
+	if oc.err != nil {
+		return oc.err
+	}
+
+	// Nothing is queued until the whole file has been checked.
+	layer, err := oc.readConfig(r, format, "")
+	if err != nil {
+		return err
+	}
+	for o, p := range layer {
+		o.loaded = append(o.loaded, p...)
+	}
+	return nil
+}
+
+// Read the config file in r, and match it to the options of oc and its
+// subcommands.  file names the file for Origin(), if known.
+func (oc *Opts) readConfig(r io.Reader, format ConfigFormat, file string) (map[*Opts][]optPending, error) {
+	var root configNode
+	var err error
+	switch format {
+	case ConfigJSON:
+		root, err = readJSONConfig(r)
+	case ConfigYAML:
+		root, err = readYAMLConfig(r)
+	case ConfigINI:
+		root, err = readINIConfig(r)
+	default:
+		return nil, fmt.Errorf("config format %v not supported", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	layer := make(map[*Opts][]optPending)
+	if err := oc.configPending(root, "", file, layer); err != nil {
+		return nil, err
+	}
+	return layer, nil
+}
+
+// Match the fields of table to oc's options and subcommands, adding
+// committers for the values to pending.  prefix is the path to table, for
+// errors, and file is the name of the file, for Origin().
+func (oc *Opts) configPending(table configNode, prefix string, file string, pending map[*Opts][]optPending) error {
+	for _, field := range table.fields {
+		key := prefix + field.key
+		if cmd, ok := oc.commands[field.key]; ok {
+			if field.node.kind != configTable {
+				return fmt.Errorf("config %s: command needs a table of options", key)
+			}
+			if err := cmd.opts.configPending(field.node, key+".", file, pending); err != nil {
+				return err
+			}
+			continue
+		}
+
+		h, info, ok := oc.lookup(field.key)
+		if !ok {
+			return fmt.Errorf("config %s: option not recognized", key)
+		}
+
+		values, lines := field.node.values, field.node.lines
+		switch field.node.kind {
+		case configList:
+			if !isArray(h) {
+				return fmt.Errorf("config %s: option takes one value, not a list", key)
+			}
+		case configTable:
+			if !isMap(h) {
+				return fmt.Errorf("config %s: option does not take a table", key)
+			}
+			values, lines = nil, nil
+			for _, f := range field.node.fields {
+				if f.node.kind != configScalar {
+					return fmt.Errorf("config %s.%s: value must be a scalar", key, f.key)
+				}
+				values = append(values, f.key+"="+f.node.values[0])
+				lines = append(lines, f.node.lines...)
+			}
+		}
+
+		// The negated form of a NegatableOption() sets the option to
+		// the opposite of its value.
+		negated := info.negation != nil && !info.hasName(field.key)
+		for i, value := range values {
+			var c optCommitter
+			var err error
+			if negated {
+				var b bool
+				b, err = strconv.ParseBool(value)
+				if err == nil {
+					c, err = info.handler.handleValue(strconv.FormatBool(!b))
+				}
+			} else {
+				c, err = h.handleValue(value)
+			}
+			if err != nil {
+				err = &InvalidValueError{Name: field.key, Value: value, Err: err}
+				return fmt.Errorf("config %s: %w", key, err)
+			}
+			origin := Origin{Kind: FromConfig, File: file, Raw: value}
+			if i < len(lines) {
+				origin.Line = lines[i]
+			}
+			pending[oc] = append(pending[oc], optPending{info, c, value, origin})
+		}
+	}
+	return nil
+}
+
+// Reads a JSON config a token at a time, to find the line of each value,
+// which decoding into a map would lose.
+type jsonReader struct {
+	dec  *json.Decoder
+	data []byte
+}
+
+// Returns the line of the last token read.  Scalars cannot span lines, so
+// this is also where the token started.
+func (jr *jsonReader) line() int {
+	return 1 + bytes.Count(jr.data[:jr.dec.InputOffset()], []byte("\n"))
+}
+
+// Returns the next token.  The end of the input is unexpected, as it is only
+// read inside the object.
+func (jr *jsonReader) token() (json.Token, error) {
+	tok, err := jr.dec.Token()
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+// Convert the JSON value starting with tok to a configNode.
+func (jr *jsonReader) node(tok json.Token) (configNode, error) {
+	line := jr.line()
+	switch tok := tok.(type) {
+	case string:
+		return configNode{kind: configScalar, values: []string{tok}, lines: []int{line}}, nil
+	case json.Number:
+		return configNode{kind: configScalar, values: []string{tok.String()}, lines: []int{line}}, nil
+	case bool:
+		return configNode{kind: configScalar, values: []string{strconv.FormatBool(tok)}, lines: []int{line}}, nil
+	case json.Delim:
+		if tok == '[' {
+			return jr.list()
+		}
+		return jr.table()
+	default:
+		return configNode{}, fmt.Errorf("line %d: null has no value", line)
+	}
+}
+
+// Convert the rest of a JSON array to a configNode.
+func (jr *jsonReader) list() (configNode, error) {
+	node := configNode{kind: configList}
+	for jr.dec.More() {
+		tok, err := jr.token()
+		if err != nil {
+			return node, err
+		}
+		item, err := jr.node(tok)
+		if err != nil {
+			return node, err
+		}
+		if item.kind != configScalar {
+			return node, fmt.Errorf("line %d: lists can only hold scalars", jr.line())
+		}
+		node.values = append(node.values, item.values...)
+		node.lines = append(node.lines, item.lines...)
+	}
+	_, err := jr.token()
+	return node, err
+}
+
+// Convert the rest of a JSON object to a configNode, with the keys in file
+// order.
+func (jr *jsonReader) table() (configNode, error) {
+	node := configNode{kind: configTable}
+	for jr.dec.More() {
+		tok, err := jr.token()
+		if err != nil {
+			return node, err
+		}
+		// The decoder only returns strings for keys.
+		key := tok.(string)
+		tok, err = jr.token()
+		if err != nil {
+			return node, err
+		}
+		sub, err := jr.node(tok)
+		if err != nil {
+			return node, fmt.Errorf("%s: %w", key, err)
+		}
+
+		// Like encoding/json, the last of repeated keys wins.
+		node.fields = slices.DeleteFunc(node.fields, func(f configField) bool { return f.key == key })
+		node.fields = append(node.fields, configField{key, sub})
+	}
+	_, err := jr.token()
+	return node, err
+}
+
+func readJSONConfig(r io.Reader) (configNode, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return configNode{}, err
+	}
+	jr := &jsonReader{dec: json.NewDecoder(bytes.NewReader(data)), data: data}
+	jr.dec.UseNumber()
+
+	tok, err := jr.dec.Token()
+	if err != nil {
+		return configNode{}, err
+	}
+	if tok != json.Delim('{') {
+		return configNode{}, errors.New("JSON config must be an object")
+	}
+	root, err := jr.table()
+	if err != nil {
+		return root, err
+	}
+	if _, err := jr.dec.Token(); !errors.Is(err, io.EOF) {
+		return root, errors.New("unexpected data after the JSON object")
+	}
+	return root, nil
+}
+
+// Convert a YAML node to a configNode.  Scalars keep the text from the file.
+func yamlNode(n *yaml.Node) (configNode, error) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return configNode{}, fmt.Errorf("line %d: null has no value", n.Line)
+		}
+		return configNode{kind: configScalar, values: []string{n.Value}, lines: []int{n.Line}}, nil
+	case yaml.SequenceNode:
+		node := configNode{kind: configList}
+		for _, item := range n.Content {
+			if item.Kind != yaml.ScalarNode {
+				return node, fmt.Errorf("line %d: lists can only hold scalars", item.Line)
+			}
+			sub, err := yamlNode(item)
+			if err != nil {
+				return node, err
+			}
+			node.values = append(node.values, sub.values...)
+			node.lines = append(node.lines, sub.lines...)
+		}
+		return node, nil
+	case yaml.MappingNode:
+		node := configNode{kind: configTable}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			sub, err := yamlNode(n.Content[i+1])
+			if err != nil {
+				return node, err
+			}
+			node.fields = append(node.fields, configField{n.Content[i].Value, sub})
+		}
+		return node, nil
+	case yaml.AliasNode:
+		return yamlNode(n.Alias)
+	default:
+		return configNode{}, fmt.Errorf("line %d: unexpected YAML", n.Line)
+	}
+}
+
+func readYAMLConfig(r io.Reader) (configNode, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			// An empty file sets nothing.
+			return configNode{kind: configTable}, nil
+		}
+		return configNode{}, err
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return configNode{}, errors.New("YAML config must be a mapping")
+	}
+	return yamlNode(doc.Content[0])
+}
+
+// Returns the table for the INI section name, like "deploy.start", creating
+// it if needed.
+func iniSection(root *configNode, name string) (*configNode, error) {
+	table := root
+	if name == "" {
+		return table, nil
+	}
+	for _, key := range strings.Split(name, ".") {
+		i := slices.IndexFunc(table.fields, func(f configField) bool { return f.key == key })
+		if i < 0 {
+			table.fields = append(table.fields, configField{key, configNode{kind: configTable}})
+			i = len(table.fields) - 1
+		}
+		table = &table.fields[i].node
+		if table.kind != configTable {
+			return nil, fmt.Errorf("section %s is also a key", name)
+		}
+	}
+	return table, nil
+}
+
+func readINIConfig(r io.Reader) (configNode, error) {
+	root := configNode{kind: configTable}
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' || text[0] == ';' {
+			continue
+		}
+		if name, ok := strings.CutPrefix(text, "["); ok {
+			name, ok = strings.CutSuffix(name, "]")
+			if !ok || strings.TrimSpace(name) == "" {
+				return root, fmt.Errorf("line %d: bad section header %q", line, text)
+			}
+			section = strings.TrimSpace(name)
+			if _, err := iniSection(&root, section); err != nil {
+				return root, fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return root, fmt.Errorf("line %d: expected key = value", line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+
+		// Tables may move as sections are added, so look up the
+		// section each time.
+		table, err := iniSection(&root, section)
+		if err != nil {
+			return root, fmt.Errorf("line %d: %w", line, err)
+		}
+		i := slices.IndexFunc(table.fields, func(f configField) bool { return f.key == key })
+		if i < 0 {
+			table.fields = append(table.fields, configField{key, configNode{kind: configScalar, values: []string{value}, lines: []int{line}}})
+		} else if node := &table.fields[i].node; node.kind != configTable {
+			// Repeated keys make a list.
+			node.kind = configList
+			node.values = append(node.values, value)
+			node.lines = append(node.lines, line)
+		} else {
+			return root, fmt.Errorf("line %d: %s is also a section", line, key)
+		}
+	}
+	return root, scanner.Err()
+}
@@ -0,0 +1,261 @@
+package opts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Options for exercising config files, with pointers to their values.
+type configTarget struct {
+	verbose  bool
+	length   int
+	ratio    float64
+	files    []string
+	labels   map[string]string
+	replicas int
+	force    bool
+}
+
+func (ct *configTarget) opts() *Opts {
+	return NewOpts().
+		NegatableOption("verbose", &ct.verbose).
+		IntOption("length", &ct.length).AKA("len").
+		FloatOption("ratio", &ct.ratio).
+		StringArrayOption("file", &ct.files).
+		StringMapOption("label", &ct.labels).
+		Command("deploy", NewOpts().
+			IntOption("replicas", &ct.replicas).
+			SimpleOption("force", &ct.force),
+			nil)
+}
+
+func TestLoadConfig(t *testing.T) {
+	configs := []struct {
+		format ConfigFormat
+		text   string
+	}{
+		{ConfigJSON, `{
+			"verbose": true,
+			"len": 11,
+			"ratio": 0.5,
+			"file": ["a", "b"],
+			"label": {"app": "web", "tier": "1"},
+			"deploy": {"replicas": "3", "force": true}
+		}`},
+		{ConfigYAML, `
+verbose: true
+len: 11
+ratio: 0.5
+file:
+  - a
+  - b
+label:
+  app: web
+  tier: 1
+deploy:
+  replicas: 3
+  force: true
+`},
+		{ConfigINI, `
+# Comments are skipped.
+verbose = true
+len = 11
+ratio = 0.5
+file = a
+file = "b"
+label = app=web
+label = tier=1
+
+[deploy]
+replicas = 3
+; So are these.
+force = true
+`},
+	}
+	for _, config := range configs {
+		var ct configTarget
+		oc := ct.opts()
+		require.Nil(t, oc.LoadConfig(strings.NewReader(config.text), config.format), config.format)
+		_, err := oc.ProcessArgs([]string{"deploy"})
+		if assert.Nil(t, err, config.format) {
+			assert.True(t, ct.verbose, config.format)
+			assert.Equal(t, 11, ct.length, config.format)
+			assert.Equal(t, 0.5, ct.ratio, config.format)
+			assert.Equal(t, []string{"a", "b"}, ct.files, config.format)
+			assert.Equal(t, map[string]string{"app": "web", "tier": "1"}, ct.labels, config.format)
+			assert.Equal(t, 3, ct.replicas, config.format)
+			assert.True(t, ct.force, config.format)
+		}
+	}
+}
+
+func TestLoadConfigNegated(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{`{"noverbose": true}`, false},
+		{`{"noverbose": false}`, true},
+	}
+	for _, test := range tests {
+		ct := configTarget{verbose: !test.want}
+		oc := ct.opts()
+		require.Nil(t, oc.LoadConfig(strings.NewReader(test.text), ConfigJSON))
+		_, err := oc.ProcessArgs([]string{"deploy"})
+		if assert.Nil(t, err, test.text) {
+			assert.Equal(t, test.want, ct.verbose, test.text)
+		}
+	}
+
+	{
+		var ct configTarget
+		err := ct.opts().LoadConfig(strings.NewReader(`{"noverbose": "maybe"}`), ConfigJSON)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "config noverbose: strconv.ParseBool")
+		}
+	}
+}
+
+func TestLoadConfigOnce(t *testing.T) {
+	var ct configTarget
+	oc := ct.opts()
+	require.Nil(t, oc.LoadConfig(strings.NewReader(`{"file": ["a"], "deploy": {"replicas": 3}}`), ConfigJSON))
+
+	// A failed ProcessArgs() leaves the config for the next one.
+	_, err := oc.ProcessArgs([]string{"--missing", "deploy"})
+	assert.NotNil(t, err)
+	_, err = oc.ProcessArgs([]string{"deploy"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a"}, ct.files)
+	assert.Equal(t, 3, ct.replicas)
+
+	// But after that it is used up.
+	ct.replicas = 1
+	_, err = oc.ProcessArgs([]string{"deploy"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a"}, ct.files)
+	assert.Equal(t, 1, ct.replicas)
+	assert.False(t, oc.IsSet("file"))
+
+	// A Parser uses it for every Parse().
+	require.Nil(t, oc.LoadConfig(strings.NewReader(`{"file": ["b"]}`), ConfigJSON))
+	p, err := oc.Compile()
+	require.Nil(t, err)
+	for range 2 {
+		r, err := p.Parse([]string{"deploy"})
+		if assert.Nil(t, err) {
+			assert.True(t, r.IsSet("file"))
+		}
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	// The command line wins, and replaces arrays.
+	{
+		var ct configTarget
+		oc := ct.opts()
+		require.Nil(t, oc.LoadConfig(strings.NewReader(`{"verbose": true, "length": 11, "file": ["a"], "ratio": 0.5}`), ConfigJSON))
+		require.Nil(t, oc.LoadConfig(strings.NewReader("length = 12\nfile = b\n"), ConfigINI))
+		_, err := oc.ProcessArgs([]string{"--noverbose", "--file", "c", "deploy"})
+		if assert.Nil(t, err) {
+			assert.False(t, ct.verbose)
+			assert.Equal(t, 12, ct.length)
+			assert.Equal(t, 0.5, ct.ratio)
+			assert.Equal(t, []string{"c"}, ct.files)
+		}
+	}
+
+	// So does the environment.
+	{
+		t.Setenv("TEST_OPTS_LENGTH", "13")
+		var ct configTarget
+		oc := ct.opts().EnvPrefix("TEST_OPTS_")
+		require.Nil(t, oc.LoadConfig(strings.NewReader(`{"length": 11, "ratio": 0.5}`), ConfigJSON))
+		_, err := oc.ProcessArgs([]string{"deploy"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 13, ct.length)
+			assert.Equal(t, 0.5, ct.ratio)
+		}
+	}
+
+	// Config values count for Required().
+	{
+		length := 7
+		oc := NewOpts().IntOption("length", &length).Required()
+		require.Nil(t, oc.LoadConfig(strings.NewReader("length: 11"), ConfigYAML))
+		_, err := oc.ProcessArgs([]string{})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, length)
+		}
+	}
+
+	// Nothing is stored if the command line fails.
+	{
+		var ct configTarget
+		oc := ct.opts()
+		require.Nil(t, oc.LoadConfig(strings.NewReader(`{"length": 11}`), ConfigJSON))
+		_, err := oc.ProcessArgs([]string{"--length=twelve", "deploy"})
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, ct.length)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		format ConfigFormat
+		text   string
+		want   string
+	}{
+		{ConfigJSON, `{"length": 11, "width": 3}`, "config width: option not recognized"},
+		{ConfigJSON, `{"length": "eleven"}`, "config length: strconv.Atoi"},
+		{ConfigJSON, `{"length": [1, 2]}`, "config length: option takes one value, not a list"},
+		{ConfigJSON, `{"length": null}`, "null has no value"},
+		{ConfigJSON, `{"length": 11`, "config: unexpected end of JSON input"},
+		{ConfigJSON, `{"length": [1`, "config: length: unexpected end of JSON input"},
+		{ConfigJSON, `{`, "config: unexpected"},
+		{ConfigJSON, `{"length": 11} 12`, "unexpected data after the JSON object"},
+		{ConfigJSON, `[1]`, "must be an object"},
+		{ConfigJSON, `{"deploy": 3}`, "config deploy: command needs a table"},
+		{ConfigJSON, `{"deploy": {"replicas": "x"}}`, "config deploy.replicas: strconv.Atoi"},
+		{ConfigJSON, `{"length": {"a": "b"}}`, "config length: option does not take a table"},
+		{ConfigYAML, "length: [1, [2]]", "lists can only hold scalars"},
+		{ConfigYAML, "- length", "must be a mapping"},
+		{ConfigYAML, "length: 11\n  bad: indent", "config: yaml"},
+		{ConfigINI, "length 11", "line 1: expected key = value"},
+		{ConfigINI, "[deploy", "line 1: bad section header"},
+		{ConfigINI, "length = 11\nlength = 12", "config length: option takes one value, not a list"},
+		{ConfigINI, "deploy = 1\n[deploy]", "line 2: section deploy is also a key"},
+		{ConfigFormat(7), "", "config format ConfigFormat(7) not supported"},
+	}
+	for _, test := range tests {
+		var ct configTarget
+		oc := ct.opts()
+		err := oc.LoadConfig(strings.NewReader("verbose: true\nfile: [a]\n"), ConfigYAML)
+		require.Nil(t, err)
+
+		// A bad file leaves nothing behind, so only the first file is
+		// used.
+		err = oc.LoadConfig(strings.NewReader(test.text), test.format)
+		if assert.NotNil(t, err, test.text) {
+			assert.Contains(t, err.Error(), test.want)
+		}
+		_, err = oc.ProcessArgs([]string{"deploy"})
+		if assert.Nil(t, err, test.text) {
+			assert.True(t, ct.verbose)
+			assert.Equal(t, 0, ct.length)
+			assert.Equal(t, []string{"a"}, ct.files)
+		}
+	}
+
+	{
+		err := NewOpts().
+			Required().
+			LoadConfig(strings.NewReader("{}"), ConfigJSON)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no option")
+		}
+	}
+}
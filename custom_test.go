@@ -0,0 +1,240 @@
+package opts
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testColor int
+
+const (
+	testRed testColor = iota
+	testGreen
+	testBlue
+)
+
+func parseTestColor(s string) (testColor, error) {
+	switch s {
+	case "red":
+		return testRed, nil
+	case "green":
+		return testGreen, nil
+	case "blue":
+		return testBlue, nil
+	}
+	return testRed, errors.New("unknown color " + s)
+}
+
+func TestCustomOption(t *testing.T) {
+	{
+		stayMinute := time.Minute
+		wantSecond := time.Minute
+		args := []string{
+			"--want-second", "1s",
+			"left",
+		}
+		ret, err := NewOpts().
+			CustomOption("stay-minute", Custom(&stayMinute, time.ParseDuration)).
+			CustomOption("want-second", Custom(&wantSecond, time.ParseDuration)).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, time.Minute, stayMinute)
+			assert.Equal(t, time.Second, wantSecond)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		var addr netip.Addr
+		wantBlue := testRed
+		args := []string{
+			"--addr=127.0.0.1",
+			"--color=blue",
+			"left",
+		}
+		ret, err := NewOpts().
+			CustomOption("addr", Custom(&addr, netip.ParseAddr)).
+			CustomOption("color", Custom(&wantBlue, parseTestColor)).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, netip.MustParseAddr("127.0.0.1"), addr)
+			assert.Equal(t, testBlue, wantBlue)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantMinute := time.Minute
+		args := []string{
+			"--want-minute",
+		}
+		ret, err := NewOpts().
+			CustomOption("want-minute", Custom(&wantMinute, time.ParseDuration)).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "missing")
+		}
+		assert.Equal(t, []string{"--want-minute"}, ret)
+	}
+
+	// A parse failure leaves earlier options untouched.
+	{
+		stayRed := testRed
+		stayMinute := time.Minute
+		args := []string{
+			"--color", "green",
+			"--minute", "forever",
+		}
+		ret, err := NewOpts().
+			CustomOption("color", Custom(&stayRed, parseTestColor)).
+			CustomOption("minute", Custom(&stayMinute, time.ParseDuration)).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "forever")
+		}
+		assert.Equal(t, testRed, stayRed)
+		assert.Equal(t, time.Minute, stayMinute)
+		assert.Equal(t, []string{"--color", "green", "--minute", "forever"}, ret)
+	}
+
+	{
+		_, err := NewOpts().
+			CustomOption("empty", CustomValue{}).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no value")
+		}
+	}
+}
+
+func TestOptionalCustomOption(t *testing.T) {
+	{
+		stayRed := testRed
+		wantGreen := testRed
+		args := []string{
+			"--want-green", "green",
+			"left",
+		}
+		ret, err := NewOpts().
+			CustomOption("stay-red", OptionalCustom(&stayRed, testBlue, parseTestColor)).
+			CustomOption("want-green", OptionalCustom(&wantGreen, testBlue, parseTestColor)).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, testRed, stayRed)
+			assert.Equal(t, testGreen, wantGreen)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantBlue := testRed
+		args := []string{
+			"--want-blue", "--",
+			"left",
+		}
+		ret, err := NewOpts().
+			CustomOption("want-blue", OptionalCustom(&wantBlue, testBlue, parseTestColor)).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, testBlue, wantBlue)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+}
+
+func TestCustomArrayOption(t *testing.T) {
+	{
+		stayEmpty := []time.Duration{}
+		wantTwo := []time.Duration{}
+		args := []string{
+			"--want-two", "1s",
+			"--want-two=1m",
+			"left",
+		}
+		ret, err := NewOpts().
+			CustomOption("stay-empty", CustomArray(&stayEmpty, time.ParseDuration)).
+			CustomOption("want-two", CustomArray(&wantTwo, time.ParseDuration)).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Empty(t, stayEmpty)
+			assert.Equal(t, []time.Duration{time.Second, time.Minute}, wantTwo)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		stayEmpty := []time.Duration{}
+		args := []string{
+			"--stay-empty", "1s",
+			"--stay-empty", "forever",
+		}
+		_, err := NewOpts().
+			CustomOption("stay-empty", CustomArray(&stayEmpty, time.ParseDuration)).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "forever")
+		}
+		assert.Empty(t, stayEmpty)
+	}
+
+	{
+		sameColors := []testColor{}
+		_, err := NewOpts().
+			CustomOption("colors", CustomArray(&sameColors, parseTestColor)).
+			CustomOption("colours", CustomArray(&sameColors, parseTestColor)).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "same pointer")
+		}
+	}
+}
+
+// Custom options storing a slice type, like net.IP, take one value.
+func TestCustomSliceType(t *testing.T) {
+	parseIP := func(s string) (net.IP, error) {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.New("bad address " + s)
+		}
+		return ip, nil
+	}
+
+	{
+		var ip net.IP
+		var b strings.Builder
+		err := NewOpts().
+			CustomOption("addr", Custom(&ip, parseIP)).Help("Address.").
+			Usage(&b)
+		if assert.Nil(t, err) {
+			assert.NotContains(t, b.String(), "repeatable")
+		}
+	}
+
+	{
+		var ip net.IP
+		_, err := NewOpts().
+			CustomOption("addr", Custom(&ip, parseIP)).AtLeast(2).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "takes one value")
+		}
+	}
+
+	{
+		t.Setenv("TEST_OPTS_ADDR", "1.2.3.4,5.6.7.8")
+		var ip net.IP
+		_, err := NewOpts().
+			CustomOption("addr", Custom(&ip, parseIP)).Env("TEST_OPTS_ADDR").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "bad address 1.2.3.4,5.6.7.8")
+		}
+		assert.Nil(t, ip)
+	}
+}
@@ -30,14 +30,73 @@ Output:
 
 # Command line flag syntax
 
-opts currently only handles --option style of options, no groups of
-single-chararacter options.  Bare -- ends option processing.  Boolean
+Options are --option style, and can also have a single-character -o
+short form added with Short().  Short options can be bundled, so -vvx is
+-v -v -x, and -l24 is -l 24.  Other long names can be added with AKA() or
+Alias(), and AutoAbbrev() allows any unique prefix of a long name.  Bare --
+ends option processing.  By default, so does the first
+argument which is not an option, but with Permute() options and other
+arguments can be mixed.  PassThrough() returns unknown options with the
+other arguments, rather than failing.  ResponseFiles() expands @file
+arguments into the arguments in the file, and ProcessString() splits a
+string into arguments like a shell would.  Boolean
 options can be negatable or simple, with no parameters (so --option or
 --nooption).  Options with parameters can be --option=value or --option
 value.  Optional options deliver the provided default if --option is seen
 with no next argument, or where the next argument itself looks like another
 option, or where the next argument is --.
 
+# Subcommands
+
+Command() adds git-style subcommands, each with their own Opts.  The first
+argument after the options selects the subcommand, which processes the
+options following it.  Options marked Persistent() are also valid after the
+subcommand.  Run() processes the arguments and calls the selected
+subcommand.
+
+# Environment
+
+Options can fall back on environment variables, either named individually
+with Env() or derived from the option name with EnvPrefix().  LoadConfig()
+reads option values from JSON, YAML or INI files, which the environment
+overrides.  Values from the command line always win.  Sources() adds more
+layers of config files beneath these.  After ProcessArgs(), IsSet() and Visit()
+tell options which were given from those left at their defaults, and
+Origin() and Provenance() say where each value came from.
+
+# Parsing more than once
+
+Each ProcessArgs() starts afresh, so an Opts can process one set of
+arguments after another, like the lines of a REPL.  Compile() returns a
+Parser whose Parse() leaves the option pointers alone, and returns the values
+in a Result.  A Parser can be shared by several goroutines, and a Result is
+only stored to the pointers by Result.Commit().
+
+# Errors
+
+Errors from ProcessArgs() have types like [UnknownOptionError] and
+[InvalidValueError], which say which argument caused them.  Bad values from
+the environment and config files are also [InvalidValueError].  Unknown
+options suggest similar option names, see SuggestDistance().  With
+CollectErrors(), every error in the arguments is returned, not just the
+first.
+
+# Usage text
+
+Options can be described with Help() and Metavar(), and Usage() writes a
+table of the options, including defaults taken from the option pointers.
+ManPage() and Markdown() write the same details as a section 1 man page or
+a Markdown reference page, including subcommands and environment variables.
+
+# Shell completion
+
+BashCompletion(), ZshCompletion() and FishCompletion() write completion
+scripts for the options.  CompleteChoices() and CompleteFiles() provide hints
+for completing option values.  For values which need to be looked up when
+completing, CompleteFunc() registers a callback, and running the program
+with a first argument of __complete writes completions for the rest of the
+command line.
+
 # Why not flag package?
 
 No reason, I'm not the flag police.  Mostly with [flag] I was frustrated by
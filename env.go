@@ -0,0 +1,103 @@
+package opts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Fall back on environment variable name for the preceding option, like:
+//
+//	IntOption("port", &port).Env("APP_PORT")
+//
+// The variable is only used if the option is not on the command line.
+// Values are parsed like command-line values, except that options without
+// values take true or false, and counting options take the count.  Array
+// options split the value using EnvSeparator().
+func (oc *Opts) Env(name string) *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("environment variable %s has no option to follow", name))
+		return oc
+	}
+	oc.last.env = name
+	return oc
+}
+
+// Fall back on environment variables for every option without an Env(),
+// named by prefix followed by the option name, upper-cased and with - changed
+// to _.  So with EnvPrefix("APP_"), --dry-run falls back on APP_DRY_RUN.
+func (oc *Opts) EnvPrefix(prefix string) *Opts {
+	oc.envPrefix = prefix
+	return oc
+}
+
+// Split environment variable values for array options on sep.  The default
+// is ",".
+func (oc *Opts) EnvSeparator(sep string) *Opts {
+	if sep == "" {
+		oc.setError(fmt.Errorf("environment separator cannot be empty"))
+		return oc
+	}
+	oc.envSeparator = sep
+	return oc
+}
+
+// Returns the name of the environment variable for info, or "" if there is
+// none.
+func (oc *Opts) envName(info *optInfo) string {
+	if info.env != "" {
+		return info.env
+	}
+	if oc.envPrefix == "" {
+		return ""
+	}
+	return oc.envPrefix + strings.ToUpper(strings.ReplaceAll(info.name, "-", "_"))
+}
+
+// Returns the values from the environment for the options of ps.chain.
+// Options which were on the command line are skipped, so that their
+// variables are not even parsed, unless the environment's values are kept by
+// AppendLayers().
+func envLayer(ps *optParse) (map[*Opts][]optPending, error) {
+	seen := make(map[*optInfo]bool)
+	for _, queued := range ps.queued {
+		for _, p := range queued {
+			seen[p.info] = true
+		}
+	}
+
+	layer := make(map[*Opts][]optPending)
+	for _, oc := range ps.chain {
+		for _, info := range oc.options() {
+			if seen[info] && !info.appendLayers {
+				continue
+			}
+			name := oc.envName(info)
+			if name == "" {
+				continue
+			}
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+
+			values := []string{value}
+			if isArray(info.handler) {
+				values = nil
+				if value != "" {
+					values = strings.Split(value, oc.envSeparator)
+				}
+			}
+			for _, v := range values {
+				c, err := info.handler.handleValue(v)
+				if err != nil {
+					err = &InvalidValueError{Name: info.name, Value: v, Err: err}
+					return nil, fmt.Errorf("environment variable %s: %w", name, err)
+				}
+				origin := Origin{Kind: FromEnv, Env: name, Raw: value}
+				layer[oc] = append(layer[oc], optPending{info, c, v, origin})
+			}
+		}
+	}
+	return layer, nil
+}
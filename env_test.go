@@ -0,0 +1,194 @@
+package opts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("TEST_OPTS_PORT", "8080")
+	t.Setenv("TEST_OPTS_NAME", "chaos")
+	t.Setenv("TEST_OPTS_VERBOSE", "true")
+	t.Setenv("TEST_OPTS_QUIET", "false")
+	t.Setenv("TEST_OPTS_DEBUG", "3")
+	t.Setenv("TEST_OPTS_TIMEOUT", "1s")
+
+	{
+		wantPort := 80
+		wantChaos := "calm"
+		wantTrue := false
+		wantFalse := true
+		wantThree := 0
+		wantSecond := time.Minute
+		stayCalm := "calm"
+		args := []string{
+			"left",
+		}
+		ret, err := NewOpts().
+			IntOption("port", &wantPort).Env("TEST_OPTS_PORT").
+			StringOption("name", &wantChaos).Env("TEST_OPTS_NAME").
+			SimpleOption("verbose", &wantTrue).Env("TEST_OPTS_VERBOSE").
+			NegatableOption("quiet", &wantFalse).Env("TEST_OPTS_QUIET").
+			CountingOption("debug", &wantThree).Env("TEST_OPTS_DEBUG").
+			CustomOption("timeout", Custom(&wantSecond, time.ParseDuration)).Env("TEST_OPTS_TIMEOUT").
+			StringOption("unset", &stayCalm).Env("TEST_OPTS_UNSET").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 8080, wantPort)
+			assert.Equal(t, "chaos", wantChaos)
+			assert.True(t, wantTrue)
+			assert.False(t, wantFalse)
+			assert.Equal(t, 3, wantThree)
+			assert.Equal(t, time.Second, wantSecond)
+			assert.Equal(t, "calm", stayCalm)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	// The command line wins.
+	{
+		wantPort := 80
+		wantFalse := true
+		args := []string{
+			"--port", "443",
+			"--noverbose",
+			"left",
+		}
+		ret, err := NewOpts().
+			IntOption("port", &wantPort).Env("TEST_OPTS_PORT").
+			NegatableOption("verbose", &wantFalse).Env("TEST_OPTS_VERBOSE").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 443, wantPort)
+			assert.False(t, wantFalse)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+}
+
+func TestEnvError(t *testing.T) {
+	t.Setenv("TEST_OPTS_PORT", "eighty")
+	t.Setenv("TEST_OPTS_VERBOSE", "yes please")
+
+	{
+		stayPort := 80
+		stayChaos := "calm"
+		args := []string{
+			"--name", "chaos",
+		}
+		_, err := NewOpts().
+			IntOption("port", &stayPort).Env("TEST_OPTS_PORT").
+			StringOption("name", &stayChaos).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "TEST_OPTS_PORT")
+			assert.Contains(t, err.Error(), "invalid syntax")
+		}
+		assert.Equal(t, 80, stayPort)
+		assert.Equal(t, "calm", stayChaos)
+	}
+
+	{
+		stayFalse := false
+		_, err := NewOpts().
+			SimpleOption("verbose", &stayFalse).Env("TEST_OPTS_VERBOSE").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "TEST_OPTS_VERBOSE")
+		}
+		assert.False(t, stayFalse)
+	}
+
+	// Not consulted when the command line has the option.
+	{
+		wantPort := 80
+		_, err := NewOpts().
+			IntOption("port", &wantPort).Env("TEST_OPTS_PORT").
+			ProcessArgs([]string{"--port=443"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 443, wantPort)
+		}
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	t.Setenv("TEST_OPTS_DRY_RUN", "1")
+	t.Setenv("TEST_OPTS_LENGTH", "24")
+	t.Setenv("OTHER_LENGTH", "11")
+
+	wantTrue := false
+	wantEleven := 7
+	stayCalm := "calm"
+	_, err := NewOpts().
+		EnvPrefix("TEST_OPTS_").
+		SimpleOption("dry-run", &wantTrue).
+		IntOption("length", &wantEleven).Env("OTHER_LENGTH").
+		StringOption("name", &stayCalm).
+		ProcessArgs([]string{})
+	if assert.Nil(t, err) {
+		assert.True(t, wantTrue)
+		assert.Equal(t, 11, wantEleven)
+		assert.Equal(t, "calm", stayCalm)
+	}
+}
+
+func TestEnvArray(t *testing.T) {
+	t.Setenv("TEST_OPTS_NAMES", "seven,eleven")
+	t.Setenv("TEST_OPTS_NUMBERS", "7:11")
+	t.Setenv("TEST_OPTS_EMPTY", "")
+
+	{
+		wantTwo := []string{}
+		sevenEleven := []int{}
+		stayEmpty := []string{}
+		_, err := NewOpts().
+			StringArrayOption("names", &wantTwo).Env("TEST_OPTS_NAMES").
+			StringArrayOption("empty", &stayEmpty).Env("TEST_OPTS_EMPTY").
+			ProcessArgs([]string{})
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"seven", "eleven"}, wantTwo)
+			assert.Empty(t, stayEmpty)
+		}
+
+		_, err = NewOpts().
+			EnvSeparator(":").
+			IntArrayOption("numbers", &sevenEleven).Env("TEST_OPTS_NUMBERS").
+			ProcessArgs([]string{})
+		if assert.Nil(t, err) {
+			assert.Equal(t, []int{7, 11}, sevenEleven)
+		}
+	}
+
+	// The command line replaces the environment, rather than appending.
+	{
+		wantOne := []string{}
+		_, err := NewOpts().
+			StringArrayOption("names", &wantOne).Env("TEST_OPTS_NAMES").
+			ProcessArgs([]string{"--names", "chaos"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"chaos"}, wantOne)
+		}
+	}
+
+	{
+		sevenEleven := []int{}
+		_, err := NewOpts().
+			IntArrayOption("numbers", &sevenEleven).Env("TEST_OPTS_NUMBERS").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "TEST_OPTS_NUMBERS")
+		}
+		assert.Empty(t, sevenEleven)
+	}
+
+	{
+		_, err := NewOpts().
+			EnvSeparator("").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "separator")
+		}
+	}
+}
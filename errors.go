@@ -0,0 +1,165 @@
+package opts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Option names in errors are the long name without the dashes, like length
+// for --length, or the short flag with its dash, like -l.  Arg is the
+// argument to ProcessArgs() which caused the error, like --length=twelve or
+// -vl, and Index is its position in the arguments.  For subcommands, Index
+// counts from the start of the arguments given to the top-level Opts.
+
+// UnknownOptionError is returned by ProcessArgs() for an argument which
+// looks like an option, but is not defined.  Suggestions are the defined
+// long flags closest to Name, like --length for lenght, see
+// SuggestDistance().
+type UnknownOptionError struct {
+	Name        string
+	Arg         string
+	Index       int
+	Suggestions []string
+}
+
+func (e *UnknownOptionError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("arg %s not recognized", e.Name)
+	}
+	return fmt.Sprintf("arg %s not recognized, did you mean %s?", e.Name, orList(e.Suggestions))
+}
+
+func (e *UnknownOptionError) setArg(arg string, index int) {
+	e.Arg, e.Index = arg, index
+}
+
+// AmbiguousOptionError is returned by ProcessArgs() with AutoAbbrev() for an
+// abbreviation of more than one long flag.  Candidates are the flags it could
+// be, like --verbose and --version for --ver.
+type AmbiguousOptionError struct {
+	Name       string
+	Arg        string
+	Index      int
+	Candidates []string
+}
+
+func (e *AmbiguousOptionError) Error() string {
+	return fmt.Sprintf("arg %s is ambiguous, could be %s", e.Name, strings.Join(e.Candidates, ", "))
+}
+
+func (e *AmbiguousOptionError) setArg(arg string, index int) {
+	e.Arg, e.Index = arg, index
+}
+
+// MissingArgumentError is returned by ProcessArgs() for an option which
+// requires a value, but is the last argument.
+type MissingArgumentError struct {
+	Name  string
+	Arg   string
+	Index int
+}
+
+func (e *MissingArgumentError) Error() string {
+	return fmt.Sprintf("arg %s missing required argument", e.Name)
+}
+
+func (e *MissingArgumentError) setArg(arg string, index int) {
+	e.Arg, e.Index = arg, index
+}
+
+// InvalidValueError is returned by ProcessArgs() when an option's value
+// cannot be parsed.  Err is the parser's error, like a [strconv.NumError].
+//
+// Bad values from the environment or config files are wrapped with the name
+// of the variable or key, and have no Arg.  Name is the option, or the key
+// for config files, and Value is the text which failed to parse.
+type InvalidValueError struct {
+	Name  string
+	Value string
+	Arg   string
+	Index int
+	Err   error
+}
+
+func (e *InvalidValueError) Error() string {
+	if e.Arg == "" {
+		// The wrapping error says where the value came from.
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("arg %s: %v", e.Name, e.Err)
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+func (e *InvalidValueError) setArg(arg string, index int) {
+	e.Arg, e.Index = arg, index
+}
+
+// DuplicateOptionError is returned when building the Opts adds a name which
+// already exists.  Short options are reported with their dash, like -l.
+type DuplicateOptionError struct {
+	Name string
+}
+
+func (e *DuplicateOptionError) Error() string {
+	if strings.HasPrefix(e.Name, "-") {
+		return fmt.Sprintf("short option %s already exists", e.Name)
+	}
+	return fmt.Sprintf("option %s already exists", e.Name)
+}
+
+// PointerConflictError is returned when two options of the Opts store to the
+// same pointer.
+type PointerConflictError struct {
+	Name  string
+	Other string
+}
+
+func (e *PointerConflictError) Error() string {
+	return fmt.Sprintf("%s and %s use the same pointer", e.Name, e.Other)
+}
+
+// Join items like "a", "a or b", or "a, b or c".
+func orList(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
+
+// Returns the errors joined with [errors.Join], flattening errors which were
+// already joined.  nil errors are dropped, and a single error is returned
+// as-is.
+func joinErrors(errs []error) error {
+	var flat []error
+	for _, err := range errs {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			flat = append(flat, joined.Unwrap()...)
+		} else if err != nil {
+			flat = append(flat, err)
+		}
+	}
+	if len(flat) == 1 {
+		return flat[0]
+	}
+	return errors.Join(flat...)
+}
+
+// Record the argument which caused err, for the error types which have it.
+// For joined errors, each of them is updated.
+func atArg(err error, arg string, index int) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			atArg(err, arg, index)
+		}
+		return err
+	}
+	var ae interface{ setArg(string, int) }
+	if errors.As(err, &ae) {
+		ae.setArg(arg, index)
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package opts
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownOptionError(t *testing.T) {
+	{
+		verbose := false
+		_, err := NewOpts().
+			SimpleOption("verbose", &verbose).
+			ProcessArgs([]string{"--verbose", "--lenght=11"})
+		var uerr *UnknownOptionError
+		if assert.True(t, errors.As(err, &uerr)) {
+			assert.Equal(t, "lenght", uerr.Name)
+			assert.Equal(t, "--lenght=11", uerr.Arg)
+			assert.Equal(t, 1, uerr.Index)
+			assert.Equal(t, "arg lenght not recognized", err.Error())
+		}
+	}
+
+	{
+		verbose := false
+		_, err := NewOpts().
+			SimpleOption("verbose", &verbose).Short("v").
+			ProcessArgs([]string{"-v", "-vx"})
+		var uerr *UnknownOptionError
+		if assert.True(t, errors.As(err, &uerr)) {
+			assert.Equal(t, "-x", uerr.Name)
+			assert.Equal(t, "-vx", uerr.Arg)
+			assert.Equal(t, 1, uerr.Index)
+		}
+	}
+
+	// Subcommands count from the start of all the arguments.
+	{
+		verbose := false
+		force := false
+		_, err := NewOpts().
+			SimpleOption("verbose", &verbose).
+			Command("deploy", NewOpts().SimpleOption("force", &force), nil).
+			ProcessArgs([]string{"--verbose", "deploy", "--force", "--forse"})
+		var uerr *UnknownOptionError
+		if assert.True(t, errors.As(err, &uerr)) {
+			assert.Equal(t, "forse", uerr.Name)
+			assert.Equal(t, 3, uerr.Index)
+		}
+	}
+}
+
+func TestMissingArgumentError(t *testing.T) {
+	length := 7
+	_, err := NewOpts().
+		IntOption("length", &length).Short("l").
+		ProcessArgs([]string{"--length=11", "-l"})
+	var merr *MissingArgumentError
+	if assert.True(t, errors.As(err, &merr)) {
+		assert.Equal(t, "-l", merr.Name)
+		assert.Equal(t, "-l", merr.Arg)
+		assert.Equal(t, 1, merr.Index)
+		assert.Contains(t, err.Error(), "missing")
+	}
+}
+
+func TestInvalidValueError(t *testing.T) {
+	{
+		stayEleven := 11
+		_, err := NewOpts().
+			IntOption("length", &stayEleven).Short("l").
+			ProcessArgs([]string{"-l", "twelve"})
+		var verr *InvalidValueError
+		if assert.True(t, errors.As(err, &verr)) {
+			assert.Equal(t, "-l", verr.Name)
+			assert.Equal(t, "twelve", verr.Value)
+			assert.Equal(t, "-l", verr.Arg)
+			assert.Equal(t, 0, verr.Index)
+			assert.Contains(t, err.Error(), "invalid syntax")
+		}
+		assert.True(t, errors.Is(err, strconv.ErrSyntax))
+		assert.Equal(t, 11, stayEleven)
+	}
+
+	{
+		var errOdd = errors.New("odd")
+		even := 0
+		_, err := NewOpts().
+			CustomOption("even", Custom(&even, func(s string) (int, error) {
+				v, err := strconv.Atoi(s)
+				if err == nil && v%2 != 0 {
+					err = errOdd
+				}
+				return v, err
+			})).
+			ProcessArgs([]string{"--even=3"})
+		var verr *InvalidValueError
+		if assert.True(t, errors.As(err, &verr)) {
+			assert.Equal(t, "even", verr.Name)
+			assert.Equal(t, "3", verr.Value)
+			assert.Equal(t, "--even=3", verr.Arg)
+		}
+		assert.True(t, errors.Is(err, errOdd))
+	}
+
+	// Values from the environment and config files have no Arg.
+	{
+		t.Setenv("TEST_OPTS_LENGTH", "twelve")
+		stayEleven := 11
+		_, err := NewOpts().
+			IntOption("length", &stayEleven).Env("TEST_OPTS_LENGTH").
+			ProcessArgs([]string{})
+		var verr *InvalidValueError
+		if assert.True(t, errors.As(err, &verr)) {
+			assert.Equal(t, "length", verr.Name)
+			assert.Equal(t, "twelve", verr.Value)
+			assert.Equal(t, "", verr.Arg)
+			assert.Contains(t, err.Error(), "environment variable TEST_OPTS_LENGTH: strconv.Atoi")
+		}
+		assert.True(t, errors.Is(err, strconv.ErrSyntax))
+		assert.Equal(t, 11, stayEleven)
+	}
+
+	{
+		replicas := 1
+		err := NewOpts().
+			Command("deploy", NewOpts().IntOption("replicas", &replicas), nil).
+			LoadConfig(strings.NewReader(`{"deploy": {"replicas": "x"}}`), ConfigJSON)
+		var verr *InvalidValueError
+		if assert.True(t, errors.As(err, &verr)) {
+			assert.Equal(t, "replicas", verr.Name)
+			assert.Equal(t, "x", verr.Value)
+			assert.Equal(t, "", verr.Arg)
+			assert.Contains(t, err.Error(), "config deploy.replicas: strconv.Atoi")
+		}
+	}
+}
+
+func TestAmbiguousOptionError(t *testing.T) {
+	stayFalse := false
+	stayCalm := "calm"
+	_, err := NewOpts().
+		AutoAbbrev().
+		SimpleOption("verbose", &stayFalse).
+		StringOption("version", &stayCalm).
+		ProcessArgs([]string{"--ver=1"})
+	var aerr *AmbiguousOptionError
+	if assert.True(t, errors.As(err, &aerr)) {
+		assert.Equal(t, "ver", aerr.Name)
+		assert.Equal(t, "--ver=1", aerr.Arg)
+		assert.Equal(t, 0, aerr.Index)
+		assert.Equal(t, []string{"--verbose", "--version"}, aerr.Candidates)
+		assert.Equal(t, "arg ver is ambiguous, could be --verbose, --version", err.Error())
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	{
+		length := 7
+		width := 7
+		_, err := NewOpts().
+			IntOption("length", &length).Short("l").
+			IntOption("width", &width).Short("l").
+			ProcessArgs([]string{})
+		var derr *DuplicateOptionError
+		if assert.True(t, errors.As(err, &derr)) {
+			assert.Equal(t, "-l", derr.Name)
+			assert.Equal(t, "short option -l already exists", err.Error())
+		}
+	}
+
+	{
+		length := 7
+		_, err := NewOpts().
+			IntOption("length", &length).
+			IntOption("length", &length).
+			ProcessArgs([]string{})
+		var derr *DuplicateOptionError
+		if assert.True(t, errors.As(err, &derr)) {
+			assert.Equal(t, "length", derr.Name)
+			assert.Equal(t, "option length already exists", err.Error())
+		}
+	}
+
+	{
+		length := 7
+		_, err := NewOpts().
+			IntOption("length", &length).
+			IntOption("width", &length).
+			ProcessArgs([]string{})
+		var perr *PointerConflictError
+		if assert.True(t, errors.As(err, &perr)) {
+			assert.ElementsMatch(t, []string{"length", "width"}, []string{perr.Name, perr.Other})
+			assert.Contains(t, err.Error(), "same pointer")
+		}
+	}
+}
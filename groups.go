@@ -0,0 +1,119 @@
+package opts
+
+import (
+	"fmt"
+	"strings"
+)
+
+type optGroupKind int
+
+const (
+	optExclusive optGroupKind = iota
+	optOneRequired
+	optAllOrNone
+)
+
+// A constraint on which of a set of options can be given together.
+type optGroup struct {
+	kind  optGroupKind
+	names []string
+	infos []*optInfo
+}
+
+// Returns the group's names, formatted for errors.
+func flagList(names []string) string {
+	flags := make([]string, len(names))
+	for i, name := range names {
+		flags[i] = "--" + name
+	}
+	return strings.Join(flags, ", ")
+}
+
+// Check the group against the options which were given.  order is the
+// options in the order they first appeared.
+func (g optGroup) check(order []*optInfo) error {
+	var given []string
+	for _, info := range order {
+		for i, gi := range g.infos {
+			if info == gi {
+				given = append(given, g.names[i])
+			}
+		}
+	}
+
+	switch g.kind {
+	case optExclusive:
+		if len(given) > 1 {
+			return fmt.Errorf("options %s cannot be used together", flagList(given))
+		}
+	case optOneRequired:
+		if len(given) == 0 {
+			return fmt.Errorf("one of options %s is required", flagList(g.names))
+		}
+	case optAllOrNone:
+		if len(given) > 0 && len(given) < len(g.names) {
+			return fmt.Errorf("options %s must be used together, only got %s", flagList(g.names), flagList(given))
+		}
+	}
+	return nil
+}
+
+func (oc *Opts) addGroup(kind optGroupKind, names []string) *Opts {
+	if len(names) < 2 {
+		oc.setError(fmt.Errorf("group %s needs at least two options", flagList(names)))
+		return oc
+	}
+	g := optGroup{kind: kind, names: names, infos: make([]*optInfo, len(names))}
+	for i, name := range names {
+		info, ok := oc.infos[name]
+		if !ok {
+			oc.setError(fmt.Errorf("option %s does not exist", name))
+			return oc
+		}
+		g.infos[i] = info
+	}
+	oc.groups = append(oc.groups, g)
+	return oc
+}
+
+// Allow at most one of the named options, like --json and --yaml.  Names
+// must already have been added.  The negated form of a NegatableOption()
+// counts as giving the option.  An option from a higher layer, like the
+// command line, overrides the others from lower layers, see Sources().
+func (oc *Opts) ExclusiveGroup(names ...string) *Opts {
+	return oc.addGroup(optExclusive, names)
+}
+
+// Require at least one of the named options, like --file or --url.  Names
+// must already have been added.
+func (oc *Opts) OneRequired(names ...string) *Opts {
+	return oc.addGroup(optOneRequired, names)
+}
+
+// Require all of the named options if any of them are given, like --user
+// and --password.  Names must already have been added.
+func (oc *Opts) AllOrNone(names ...string) *Opts {
+	return oc.addGroup(optAllOrNone, names)
+}
+
+// Check every group in chain against the committers in pending.  Errors
+// name the given options in the order they appeared.
+func checkGroups(chain []*Opts, pending []optPending) error {
+	var order []*optInfo
+	seen := make(map[*optInfo]bool)
+	for _, p := range pending {
+		if !seen[p.info] {
+			seen[p.info] = true
+			order = append(order, p.info)
+		}
+	}
+
+	for _, oc := range chain {
+		for _, g := range oc.groups {
+			if err := g.check(order); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
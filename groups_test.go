@@ -0,0 +1,208 @@
+package opts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExclusiveGroup(t *testing.T) {
+	{
+		wantTrue := false
+		stayFalse := false
+		_, err := NewOpts().
+			SimpleOption("json", &wantTrue).
+			SimpleOption("yaml", &stayFalse).
+			ExclusiveGroup("json", "yaml").
+			ProcessArgs([]string{"--json"})
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.False(t, stayFalse)
+		}
+	}
+
+	{
+		stayJSON := false
+		stayYAML := false
+		stayTable := false
+		args := []string{
+			"--table",
+			"--yaml",
+			"--json",
+		}
+		ret, err := NewOpts().
+			SimpleOption("json", &stayJSON).
+			SimpleOption("yaml", &stayYAML).
+			SimpleOption("table", &stayTable).
+			ExclusiveGroup("json", "yaml", "table").
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "options --table, --yaml, --json cannot be used together")
+		}
+		assert.False(t, stayJSON)
+		assert.False(t, stayYAML)
+		assert.False(t, stayTable)
+		assert.Equal(t, args, ret)
+	}
+
+	// Short flags and negated forms count as the option.
+	{
+		stayColor := true
+		stayJSON := false
+		_, err := NewOpts().
+			NegatableOption("color", &stayColor).
+			SimpleOption("json", &stayJSON).Short("j").
+			ExclusiveGroup("color", "json").
+			ProcessArgs([]string{"-j", "--nocolor"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "options --json, --color cannot be used together")
+		}
+	}
+
+	// The command line overrides the other options of the group from
+	// the environment and config files.
+	{
+		t.Setenv("TEST_OPTS_JSON", "true")
+		stayJSON := false
+		wantYAML := false
+		_, err := NewOpts().
+			SimpleOption("json", &stayJSON).Env("TEST_OPTS_JSON").
+			SimpleOption("yaml", &wantYAML).
+			ExclusiveGroup("json", "yaml").
+			ProcessArgs([]string{"--yaml"})
+		if assert.Nil(t, err) {
+			assert.False(t, stayJSON)
+			assert.True(t, wantYAML)
+		}
+	}
+	{
+		stayJSON := false
+		wantYAML := false
+		oc := NewOpts().
+			SimpleOption("json", &stayJSON).
+			SimpleOption("yaml", &wantYAML).
+			ExclusiveGroup("json", "yaml")
+		assert.Nil(t, oc.LoadConfig(strings.NewReader(`{"json": true}`), ConfigJSON))
+		_, err := oc.ProcessArgs([]string{"--yaml"})
+		if assert.Nil(t, err) {
+			assert.False(t, stayJSON)
+			assert.True(t, wantYAML)
+			assert.False(t, oc.IsSet("json"))
+		}
+	}
+
+	// But options from the same layer still conflict.
+	{
+		stayJSON := false
+		stayYAML := false
+		oc := NewOpts().
+			SimpleOption("json", &stayJSON).
+			SimpleOption("yaml", &stayYAML).
+			ExclusiveGroup("json", "yaml")
+		assert.Nil(t, oc.LoadConfig(strings.NewReader(`{"json": true, "yaml": true}`), ConfigJSON))
+		_, err := oc.ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "options --json, --yaml cannot be used together")
+		}
+	}
+}
+
+func TestOneRequired(t *testing.T) {
+	{
+		wantChaos := "calm"
+		stayCalm := "calm"
+		_, err := NewOpts().
+			StringOption("file", &wantChaos).
+			StringOption("url", &stayCalm).
+			OneRequired("file", "url").
+			ProcessArgs([]string{"--file", "chaos"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, "chaos", wantChaos)
+		}
+	}
+
+	{
+		stayCalm := "calm"
+		stayURL := "calm"
+		stayFalse := false
+		_, err := NewOpts().
+			StringOption("file", &stayCalm).
+			StringOption("url", &stayURL).
+			SimpleOption("verbose", &stayFalse).
+			OneRequired("file", "url").
+			ProcessArgs([]string{"--verbose"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "one of options --file, --url is required")
+		}
+		assert.False(t, stayFalse)
+	}
+}
+
+func TestAllOrNone(t *testing.T) {
+	{
+		stayCalm := "calm"
+		stayPassword := "calm"
+		_, err := NewOpts().
+			StringOption("user", &stayCalm).
+			StringOption("password", &stayPassword).
+			AllOrNone("user", "password").
+			ProcessArgs([]string{})
+		assert.Nil(t, err)
+	}
+
+	{
+		wantUser := "calm"
+		wantPassword := "calm"
+		_, err := NewOpts().
+			StringOption("user", &wantUser).
+			StringOption("password", &wantPassword).
+			AllOrNone("user", "password").
+			ProcessArgs([]string{"--password=secret", "--user=me"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, "me", wantUser)
+			assert.Equal(t, "secret", wantPassword)
+		}
+	}
+
+	{
+		stayUser := "calm"
+		stayPassword := "calm"
+		stayHost := "calm"
+		_, err := NewOpts().
+			StringOption("user", &stayUser).
+			StringOption("password", &stayPassword).
+			StringOption("host", &stayHost).
+			AllOrNone("user", "password", "host").
+			ProcessArgs([]string{"--host=db", "--user=me"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "options --user, --password, --host must be used together, only got --host, --user")
+		}
+		assert.Equal(t, "calm", stayUser)
+		assert.Equal(t, "calm", stayHost)
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	{
+		json := false
+		_, err := NewOpts().
+			SimpleOption("json", &json).
+			ExclusiveGroup("json", "yaml").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "yaml does not exist")
+		}
+	}
+
+	{
+		json := false
+		_, err := NewOpts().
+			SimpleOption("json", &json).
+			OneRequired("json").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "at least two")
+		}
+	}
+}
@@ -2,7 +2,9 @@ package opts
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 )
 
 // optCommitters are like simple closures called after options processing
@@ -31,6 +33,20 @@ func (o optArrayCommitter[_]) commit() {
 	*o.option = append(*o.option, o.value)
 }
 
+// Store a key and value into a map on commit.
+type optMapCommitter[T any] struct {
+	key    string
+	value  T
+	option *map[string]T
+}
+
+func (o optMapCommitter[T]) commit() {
+	if *o.option == nil {
+		*o.option = make(map[string]T)
+	}
+	(*o.option)[o.key] = o.value
+}
+
 type optType int
 
 const (
@@ -42,9 +58,13 @@ const (
 // optHandler provides a hint as to how many arguments, and a handler to call
 // with those arguments.  The handler generates an optCommitter to be called
 // later.
+//
+// handleValue is like handle, but for values from outside the command line,
+// like the environment.  There is always a value, even for optNoArg.
 type optHandler interface {
 	getType() optType
 	handle(args []string) (optCommitter, error)
+	handleValue(value string) (optCommitter, error)
 
 	checkConflict(other optHandler) bool
 	getPointer() any
@@ -60,6 +80,20 @@ func checkConflictInner[T any](ptr *T, other optHandler) bool {
 	return ptr == op
 }
 
+// Returns true for options which collect multiple values, which are arrays
+// and maps.  This goes by the handler rather than the pointer, as custom
+// options can store a single value of a slice type, like net.IP.
+func isArray(h optHandler) bool {
+	_, ok := h.(interface{ collects() })
+	return ok
+}
+
+// Returns true for options which collect key=value pairs into a map.
+func isMap(h optHandler) bool {
+	_, ok := h.(interface{ collectsPairs() })
+	return ok
+}
+
 type optBasicType interface {
 	bool | int | float64 | string
 }
@@ -115,6 +149,24 @@ func (oh optBaseHandler[T]) handle(args []string) (optCommitter, error) {
 	c := optSimpleCommitter[T]{v, oh.option}
 	return c, nil
 }
+func (oh optBaseHandler[T]) handleValue(value string) (optCommitter, error) {
+	if oh.t != optNoArg {
+		return oh.handle([]string{value})
+	}
+
+	// SimpleOption() and NegatableOption() store def when seen, so
+	// store def for true and the zero value for false.
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	var v T
+	if b {
+		v = oh.def
+	}
+	c := optSimpleCommitter[T]{v, oh.option}
+	return c, nil
+}
 func (oh optBaseHandler[_]) getPointer() any {
 	return oh.option
 }
@@ -139,12 +191,111 @@ func (oh optBaseArrayHandler[T]) handle(args []string) (optCommitter, error) {
 	c := optArrayCommitter[T]{v, oh.option}
 	return c, nil
 }
+func (oh optBaseArrayHandler[_]) handleValue(value string) (optCommitter, error) {
+	return oh.handle([]string{value})
+}
 func (oh optBaseArrayHandler[_]) getPointer() any {
 	return oh.option
 }
 func (oh optBaseArrayHandler[_]) checkConflict(other optHandler) bool {
 	return checkConflictInner(oh.option, other)
 }
+func (oh optBaseArrayHandler[_]) collects() {}
+
+type optBaseMapHandler[T optBasicType] struct {
+	t      optType
+	option *map[string]T
+}
+
+func (oh optBaseMapHandler[_]) getType() optType {
+	return oh.t
+}
+func (oh optBaseMapHandler[T]) handle(args []string) (optCommitter, error) {
+	key, value, ok := strings.Cut(args[0], "=")
+	if !ok {
+		return nil, fmt.Errorf("value %q is not key=value", args[0])
+	}
+	var v T
+	err := optParseValue(value, &v)
+	if err != nil {
+		return nil, err
+	}
+	c := optMapCommitter[T]{key, v, oh.option}
+	return c, nil
+}
+func (oh optBaseMapHandler[_]) handleValue(value string) (optCommitter, error) {
+	return oh.handle([]string{value})
+}
+func (oh optBaseMapHandler[_]) getPointer() any {
+	return oh.option
+}
+func (oh optBaseMapHandler[_]) checkConflict(other optHandler) bool {
+	return checkConflictInner(oh.option, other)
+}
+func (oh optBaseMapHandler[_]) collects()      {}
+func (oh optBaseMapHandler[_]) collectsPairs() {}
+
+// Like optBaseHandler, but the caller provides the parser.
+type optCustomHandler[T any] struct {
+	t      optType
+	option *T
+	def    T
+	parse  func(string) (T, error)
+}
+
+func (oh optCustomHandler[_]) getType() optType {
+	return oh.t
+}
+func (oh optCustomHandler[T]) handle(args []string) (optCommitter, error) {
+	v := oh.def
+	if len(args) > 0 {
+		var err error
+		v, err = oh.parse(args[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	c := optSimpleCommitter[T]{v, oh.option}
+	return c, nil
+}
+func (oh optCustomHandler[_]) handleValue(value string) (optCommitter, error) {
+	return oh.handle([]string{value})
+}
+func (oh optCustomHandler[_]) getPointer() any {
+	return oh.option
+}
+func (oh optCustomHandler[_]) checkConflict(other optHandler) bool {
+	return checkConflictInner(oh.option, other)
+}
+
+// Like optBaseArrayHandler, but the caller provides the parser.
+type optCustomArrayHandler[T any] struct {
+	t      optType
+	option *[]T
+	parse  func(string) (T, error)
+}
+
+func (oh optCustomArrayHandler[_]) getType() optType {
+	return oh.t
+}
+func (oh optCustomArrayHandler[T]) handle(args []string) (optCommitter, error) {
+	v, err := oh.parse(args[0])
+	if err != nil {
+		return nil, err
+	}
+	c := optArrayCommitter[T]{v, oh.option}
+	return c, nil
+}
+func (oh optCustomArrayHandler[_]) handleValue(value string) (optCommitter, error) {
+	return oh.handle([]string{value})
+}
+func (oh optCustomArrayHandler[_]) getPointer() any {
+	return oh.option
+}
+func (oh optCustomArrayHandler[_]) checkConflict(other optHandler) bool {
+	return checkConflictInner(oh.option, other)
+}
+func (oh optCustomArrayHandler[_]) collects() {}
 
 // Increment a pointed-to value on commit.
 type optCountingCommitter struct {
@@ -169,6 +320,15 @@ func (oh optCountingHandler) handle(args []string) (optCommitter, error) {
 	c := optCountingCommitter{oh.option}
 	return c, nil
 }
+func (oh optCountingHandler) handleValue(value string) (optCommitter, error) {
+	// A value is taken as the count.
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	c := optSimpleCommitter[int]{i, oh.option}
+	return c, nil
+}
 func (oh optCountingHandler) getPointer() any {
 	return oh.option
 }
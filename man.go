@@ -0,0 +1,232 @@
+package opts
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A subcommand, for documentation.  path is the words which select it, like
+// "tool deploy".
+type docCommand struct {
+	path string
+	opts *Opts
+}
+
+// Returns oc's subcommands and theirs, depth first in sorted order.
+func (oc *Opts) docCommands(path string) []docCommand {
+	var cmds []docCommand
+	for _, name := range oc.commandNames() {
+		sub := oc.commands[name].opts
+		cmds = append(cmds, docCommand{path + " " + name, sub})
+		cmds = append(cmds, sub.docCommands(path+" "+name)...)
+	}
+	return cmds
+}
+
+// Returns the first error from oc or any of its subcommands.
+func (oc *Opts) docError() error {
+	if oc.err != nil {
+		return oc.err
+	}
+	for _, name := range oc.commandNames() {
+		if err := oc.commands[name].opts.docError(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the flags which reach the option, with the value following the
+// last long name, like "--length", "--len=INT".  The negated forms come
+// last.  flag formats each flag, value formats the value.
+func (info *optInfo) docFlags(flag, value func(string) string) []string {
+	var flags []string
+	for _, ch := range info.shorts {
+		flags = append(flags, flag("-"+ch))
+	}
+	names := info.longNames()
+	for i, name := range names {
+		s := flag("--" + name)
+		if i == len(names)-1 {
+			switch info.handler.getType() {
+			case optOptionalArg:
+				s += "[=" + value(info.valueName()) + "]"
+			case optRequiredArg:
+				s += "=" + value(info.valueName())
+			}
+		}
+		flags = append(flags, s)
+	}
+	if info.negation != nil {
+		for _, name := range names {
+			flags = append(flags, flag("--"+negatedName(name)))
+		}
+	}
+	return flags
+}
+
+// Escape text for roff, and join it into a single line.
+func roffEscape(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.NewReplacer(`\`, `\e`, `-`, `\-`).Replace(text)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// Write the options of oc as roff tagged paragraphs.
+func (oc *Opts) manOptions(b *strings.Builder) {
+	bold := func(s string) string { return `\fB` + roffEscape(s) + `\fR` }
+	italic := func(s string) string { return `\fI` + roffEscape(s) + `\fR` }
+	for _, info := range oc.options() {
+		b.WriteString(".TP\n")
+		b.WriteString(strings.Join(info.docFlags(bold, italic), ", ") + "\n")
+		if help := info.usageHelp(oc.envName(info)); help != "" {
+			b.WriteString(roffEscape(help) + "\n")
+		}
+	}
+}
+
+// Write a section 1 man page for the program prog to w, in roff.  summary
+// is the one line description for the NAME section.  The OPTIONS section
+// lists each option like Usage() does, including the negated forms, and
+// each subcommand's options follow under COMMANDS.  Options with an
+// environment variable are also listed under ENVIRONMENT.  Like Usage(),
+// defaults are read from the option pointers.
+func (oc *Opts) ManPage(w io.Writer, prog, summary string) error {
+	if err := oc.docError(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".\\\" Generated by github.com/dshess/opts.\n")
+	fmt.Fprintf(&b, ".TH %s 1\n", roffEscape(strings.ToUpper(prog)))
+	b.WriteString(".SH NAME\n")
+	if summary != "" {
+		fmt.Fprintf(&b, "%s \\- %s\n", roffEscape(prog), roffEscape(summary))
+	} else {
+		fmt.Fprintf(&b, "%s\n", roffEscape(prog))
+	}
+
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s\n", roffEscape(prog))
+	if len(oc.commands) > 0 {
+		b.WriteString("[\\fIoptions\\fR] \\fIcommand\\fR [\\fIarguments\\fR...]\n")
+	} else {
+		b.WriteString("[\\fIoptions\\fR] [\\fIarguments\\fR...]\n")
+	}
+
+	if len(oc.options()) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		oc.manOptions(&b)
+	}
+
+	cmds := oc.docCommands(prog)
+	if len(cmds) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, cmd := range cmds {
+			fmt.Fprintf(&b, ".SS \"%s\"\n", roffEscape(cmd.path))
+			cmd.opts.manOptions(&b)
+		}
+	}
+
+	var env strings.Builder
+	for _, cmd := range append([]docCommand{{prog, oc}}, cmds...) {
+		for _, info := range cmd.opts.options() {
+			if name := cmd.opts.envName(info); name != "" {
+				fmt.Fprintf(&env, ".TP\n\\fB%s\\fR\nSets \\fB%s\\fR.\n",
+					roffEscape(name), roffEscape("--"+info.name))
+			}
+		}
+	}
+	if env.Len() > 0 {
+		b.WriteString(".SH ENVIRONMENT\n")
+		b.WriteString(env.String())
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Escape text for Markdown, and join it into a single line.
+func markdownEscape(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	r := strings.NewReplacer(
+		`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`,
+		`[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`)
+	return r.Replace(text)
+}
+
+// Write the options of oc as a Markdown list.
+func (oc *Opts) markdownOptions(b *strings.Builder) {
+	code := func(s string) string { return "`" + s + "`" }
+	plain := func(s string) string { return s }
+	for _, info := range oc.options() {
+		// The value goes inside the flag's code span.
+		flags := info.docFlags(plain, plain)
+		for i := range flags {
+			flags[i] = code(flags[i])
+		}
+		line := "* " + strings.Join(flags, ", ")
+		if help := info.usageHelp(oc.envName(info)); help != "" {
+			line += ": " + markdownEscape(help)
+		}
+		b.WriteString(line + "\n")
+	}
+}
+
+// Write a Markdown reference page for the program prog to w.  It has the
+// same sections as ManPage().
+func (oc *Opts) Markdown(w io.Writer, prog, summary string) error {
+	if err := oc.docError(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", markdownEscape(prog))
+	if summary != "" {
+		fmt.Fprintf(&b, "\n%s\n", markdownEscape(summary))
+	}
+
+	b.WriteString("\n## Synopsis\n\n")
+	if len(oc.commands) > 0 {
+		fmt.Fprintf(&b, "    %s [options] command [arguments...]\n", prog)
+	} else {
+		fmt.Fprintf(&b, "    %s [options] [arguments...]\n", prog)
+	}
+
+	if len(oc.options()) > 0 {
+		b.WriteString("\n## Options\n\n")
+		oc.markdownOptions(&b)
+	}
+
+	cmds := oc.docCommands(prog)
+	if len(cmds) > 0 {
+		b.WriteString("\n## Commands\n")
+		for _, cmd := range cmds {
+			fmt.Fprintf(&b, "\n### %s\n", markdownEscape(cmd.path))
+			if len(cmd.opts.options()) > 0 {
+				b.WriteString("\n")
+				cmd.opts.markdownOptions(&b)
+			}
+		}
+	}
+
+	var env strings.Builder
+	for _, cmd := range append([]docCommand{{prog, oc}}, cmds...) {
+		for _, info := range cmd.opts.options() {
+			if name := cmd.opts.envName(info); name != "" {
+				fmt.Fprintf(&env, "* `%s`: sets `--%s`\n", name, info.name)
+			}
+		}
+	}
+	if env.Len() > 0 {
+		b.WriteString("\n## Environment\n\n")
+		b.WriteString(env.String())
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
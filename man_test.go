@@ -0,0 +1,74 @@
+package opts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Options with subcommands and environment variables, for docs.
+func goldenCommandOpts() *Opts {
+	var (
+		verbose  = false
+		cluster  = "dev"
+		force    = false
+		replicas = 1
+		all      = false
+	)
+	return NewOpts().
+		SimpleOption("verbose", &verbose).Short("v").Persistent().Help("Be chatty.").
+		StringOption("cluster", &cluster).Env("DEPLOY_CLUSTER").Help("Cluster to talk to.").
+		Command("deploy", NewOpts().
+			EnvPrefix("DEPLOY_").
+			NegatableOption("force", &force).Help("Replace running *tasks*.").
+			IntOption("replicas", &replicas).Metavar("N").Help("Number of copies."),
+			nil).
+		Command("status", NewOpts().
+			Command("all", NewOpts().
+				SimpleOption("all", &all).Help("Include stopped tasks."),
+				nil),
+			nil)
+}
+
+func TestManPage(t *testing.T) {
+	{
+		var b strings.Builder
+		require.Nil(t, goldenOpts().ManPage(&b, "tool", "do things with data"))
+		checkGolden(t, "tool.1.golden", b.String())
+	}
+
+	{
+		var b strings.Builder
+		require.Nil(t, goldenCommandOpts().ManPage(&b, "deploy-tool", "deploy things"))
+		checkGolden(t, "commands.1.golden", b.String())
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	{
+		var b strings.Builder
+		require.Nil(t, goldenOpts().Markdown(&b, "tool", "Do things with data."))
+		checkGolden(t, "tool.md.golden", b.String())
+	}
+
+	{
+		var b strings.Builder
+		require.Nil(t, goldenCommandOpts().Markdown(&b, "deploy-tool", "Deploy things."))
+		checkGolden(t, "commands.md.golden", b.String())
+	}
+}
+
+func TestManPageErrors(t *testing.T) {
+	oc := NewOpts().
+		Command("deploy", NewOpts().Help("oops"), nil)
+	var b strings.Builder
+	if err := oc.ManPage(&b, "tool", ""); assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no option")
+	}
+	if err := oc.Markdown(&b, "tool", ""); assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "no option")
+	}
+	assert.Empty(t, b.String())
+}
@@ -0,0 +1,151 @@
+package opts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringMapOption(t *testing.T) {
+	{
+		stayEmpty := map[string]string{}
+		wantTwo := map[string]string{}
+		args := []string{
+			"--label", "app=web",
+			"--label=tier=front=end",
+			"left",
+		}
+		ret, err := NewOpts().
+			StringMapOption("stay-empty", &stayEmpty).
+			StringMapOption("label", &wantTwo).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Empty(t, stayEmpty)
+			assert.Equal(t, map[string]string{"app": "web", "tier": "front=end"}, wantTwo)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	// A nil map is created, and later values replace earlier ones.
+	{
+		var wantOne map[string]string
+		_, err := NewOpts().
+			StringMapOption("label", &wantOne).
+			ProcessArgs([]string{"--label", "app=web", "--label", "app=db"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, map[string]string{"app": "db"}, wantOne)
+		}
+	}
+
+	{
+		stayEmpty := map[string]string{}
+		args := []string{
+			"--label", "app=web",
+			"--label", "tier",
+		}
+		ret, err := NewOpts().
+			StringMapOption("label", &stayEmpty).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "not key=value")
+		}
+		assert.Empty(t, stayEmpty)
+		assert.Equal(t, args, ret)
+	}
+
+	{
+		stayEmpty := map[string]string{}
+		ret, err := NewOpts().
+			StringMapOption("label", &stayEmpty).
+			ProcessArgs([]string{"--label"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "missing")
+		}
+		assert.Equal(t, []string{"--label"}, ret)
+	}
+}
+
+func TestIntMapOption(t *testing.T) {
+	{
+		wantTwo := map[string]int{}
+		_, err := NewOpts().
+			IntMapOption("set", &wantTwo).
+			ProcessArgs([]string{"--set", "seven=7", "--set=eleven=11"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, map[string]int{"seven": 7, "eleven": 11}, wantTwo)
+		}
+	}
+
+	{
+		stayEmpty := map[string]int{}
+		_, err := NewOpts().
+			IntMapOption("set", &stayEmpty).
+			ProcessArgs([]string{"--set", "seven=7", "--set", "eleven=twelve"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "invalid syntax")
+		}
+		assert.Empty(t, stayEmpty)
+	}
+}
+
+func TestFloatMapOption(t *testing.T) {
+	{
+		wantTwo := map[string]float64{}
+		_, err := NewOpts().
+			FloatMapOption("set", &wantTwo).
+			ProcessArgs([]string{"--set", "half=0.5", "--set=eleven=11"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, map[string]float64{"half": 0.5, "eleven": 11.0}, wantTwo)
+		}
+	}
+
+	{
+		stayEmpty := map[string]float64{}
+		_, err := NewOpts().
+			FloatMapOption("set", &stayEmpty).
+			ProcessArgs([]string{"--set", "half"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "not key=value")
+		}
+		assert.Empty(t, stayEmpty)
+	}
+}
+
+func TestMapOptionConflict(t *testing.T) {
+	sameMap := map[string]int{}
+	_, err := NewOpts().
+		IntMapOption("set", &sameMap).
+		IntMapOption("define", &sameMap).
+		ProcessArgs([]string{})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "same pointer")
+	}
+}
+
+func TestMapOptionEnv(t *testing.T) {
+	t.Setenv("TEST_OPTS_LABELS", "app=web,tier=front")
+	wantTwo := map[string]string{}
+	_, err := NewOpts().
+		StringMapOption("label", &wantTwo).Env("TEST_OPTS_LABELS").
+		ProcessArgs([]string{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, map[string]string{"app": "web", "tier": "front"}, wantTwo)
+	}
+}
+
+func TestMapOptionUsage(t *testing.T) {
+	labels := map[string]string{}
+	sizes := map[string]int{"small": 1}
+	var b strings.Builder
+	err := NewOpts().
+		StringMapOption("label", &labels).
+		IntMapOption("size", &sizes).
+		Usage(&b)
+	require.Nil(t, err)
+	assert.Equal(t, ""+
+		"  --label=KEY=STRING  (repeatable)\n"+
+		"  --size=KEY=INT      (repeatable) (default: map[small:1])\n",
+		b.String())
+}
@@ -1,8 +1,10 @@
 package opts
 
 import (
-	"fmt"
+	"io/fs"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -24,19 +26,199 @@ type Opts struct {
 	// <flag name> => <handler for that flag>
 	handlers map[string]optHandler
 
-	// Defer updates until after all options are processed.
-	committers []optCommitter
+	// <flag name> => <option reached by that flag>
+	infos map[string]*optInfo
+
+	// <short flag character> => <flag name>
+	shorts map[string]string
+
+	// The most recently added option, for builders like Short() which
+	// modify the option they follow.
+	last *optInfo
+
+	// Environment variable names are derived from option names using
+	// this prefix, see EnvPrefix().
+	envPrefix string
+
+	// Environment variable values for array options are split on this.
+	envSeparator string
+
+	// Allow options after non-option arguments, see Permute().
+	permute bool
+
+	// Allow abbreviated long options, see AutoAbbrev().
+	autoAbbrev bool
+
+	// Return unknown options rather than failing, see PassThrough().
+	passThrough bool
+
+	// Expand @file arguments from here, see ResponseFiles().
+	responseFS fs.FS
+
+	// Keep going after errors in the arguments, see CollectErrors().
+	collectErrors bool
+
+	// Unknown options suggest names within this edit distance, see
+	// SuggestDistance().
+	suggestDistance int
+
+	// Constraints between options, see ExclusiveGroup() and friends.
+	groups []optGroup
+
+	// <subcommand name> => <subcommand>
+	commands map[string]*optCommand
+
+	// For subcommands, the Opts they were added to.
+	parent *Opts
+
+	// The subcommand selected by the last ProcessArgs().
+	selected string
+
+	// Committers from config files for the next ProcessArgs(), see
+	// LoadConfig().
+	loaded []optPending
+
+	// Layers of values beneath the config files, see Sources().
+	sources []Source
+
+	// The committers stored by the last ProcessArgs(), by option, for
+	// Origin() and IsSet().
+	stored map[*optInfo][]optPending
+}
+
+// The state of parsing one set of arguments.  This is kept out of Opts, so
+// that nothing carries over from one ProcessArgs() to the next, and so that a
+// Parser can be used by several goroutines at once.
+type optParse struct {
+	// Committers from the arguments, by the Opts which parsed them.
+	// Updates are deferred until after all options are processed.
+	queued map[*Opts][]optPending
+
+	// The Opts used, which is the top-level Opts followed by any
+	// selected subcommands.
+	chain []*Opts
+
+	// The names of the selected subcommands.
+	commands []string
+}
+
+// An optCommitter tagged with the option which generated it, the text of
+// its value, and where the value came from.
+type optPending struct {
+	info      *optInfo
+	committer optCommitter
+	value     string
+	origin    Origin
+}
+
+// optInfo tracks an option as the caller described it, as opposed to the
+// individual flag names which reach it.
+type optInfo struct {
+	name    string
+	aliases []string
+	shorts  []string
+	handler optHandler
+
+	// Handler for --no<name>, for NegatableOption().
+	negation optHandler
+
+	// For Usage().
+	help    string
+	metavar string
+
+	// Environment variable to fall back on, see Env().
+	env string
+
+	// Also valid after a subcommand, see Persistent().
+	persistent bool
+
+	// Minimum number of values, see Required() and AtLeast().
+	minCount int
+
+	// Keep array values from every layer, see AppendLayers().
+	appendLayers bool
+
+	// Value hints for shell completion, see CompleteChoices() and
+	// CompleteFiles().
+	choices   []string
+	files     bool
+	completer func(prefix string) []string
+}
+
+// Returns true if name is the option's name or one of its aliases, rather
+// than a negated form.
+func (info *optInfo) hasName(name string) bool {
+	return name == info.name || slices.Contains(info.aliases, name)
 }
 
 // Generates the root structure for collecting argument descriptions.
 func NewOpts() *Opts {
 	return &Opts{
-		err:        nil,
-		handlers:   make(map[string]optHandler),
-		committers: make([]optCommitter, 0, 10),
+		err:          nil,
+		handlers:     make(map[string]optHandler),
+		infos:        make(map[string]*optInfo),
+		commands:     make(map[string]*optCommand),
+		shorts:       make(map[string]string),
+		envSeparator: ",",
+
+		suggestDistance: defaultSuggestDistance,
 	}
 }
 
+// Keep processing arguments after an error, rather than stopping at the
+// first.  ProcessArgs() then returns every unknown option, bad value and
+// missing argument joined together, as with [errors.Join].  Each can be
+// found with [errors.As], or listed by unwrapping with Unwrap() []error.  As
+// usual, nothing is stored if there are any errors.
+//
+// An unknown option is skipped, so a following value is taken as an
+// argument, which ends option processing unless Permute() is used.  This
+// also applies to the subcommands of oc.
+func (oc *Opts) CollectErrors() *Opts {
+	oc.collectErrors = true
+	return oc
+}
+
+// Returns true if oc or an Opts it is a subcommand of is collecting errors.
+func (oc *Opts) collecting() bool {
+	for o := oc; o != nil; o = o.parent {
+		if o.collectErrors {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow options to be mixed with other arguments, like Getopt::Long's
+// "permute".  Options are processed wherever they appear, and ProcessArgs()
+// returns the other arguments in their original order.  -- still ends option
+// processing.
+//
+// Opts with subcommands always stop at the first non-option argument, which
+// is the subcommand.
+func (oc *Opts) Permute() *Opts {
+	oc.permute = true
+	return oc
+}
+
+// Stop processing options at the first non-option argument, like
+// Getopt::Long's "require_order".  This is the default.
+func (oc *Opts) RequireOrder() *Opts {
+	oc.permute = false
+	return oc
+}
+
+// Keep unknown options in the args returned by ProcessArgs(), rather than
+// failing, like Getopt::Long's "pass_through".  Known options are still
+// processed.  Unknown options keep their position relative to other returned
+// args, but any value for them which is not attached with = is just another
+// argument.  A bundle of short options is kept whole if it has any unknown
+// options before the first option taking a value.
+func (oc *Opts) PassThrough() *Opts {
+	oc.passThrough = true
+	return oc
+}
+
 func (oc *Opts) setError(err error) {
 	if oc.err == nil {
 		oc.err = err
@@ -44,53 +226,70 @@ func (oc *Opts) setError(err error) {
 }
 
 func (oc *Opts) addOption(name string, oh optHandler) *Opts {
-	if _, ok := oc.handlers[name]; ok {
-		oc.setError(fmt.Errorf("option %s already exists", name))
-	} else {
-		oc.handlers[name] = oh
+	oc.last = &optInfo{name: name, handler: oh}
+	return oc.addName(name, oh, oc.last)
+}
+
+// Add the --no<name> form of the most recently added option.
+func (oc *Opts) addNegation(name string, oh optHandler) *Opts {
+	oc.last.negation = oh
+	return oc.addName(negatedName(name), oh, oc.last)
+}
+
+// Add alias as another name for info, including the negated form for
+// negatable options.
+func (oc *Opts) addAlias(info *optInfo, alias string) *Opts {
+	info.aliases = append(info.aliases, alias)
+	oc.addName(alias, info.handler, info)
+	if info.negation != nil {
+		oc.addName(negatedName(alias), info.negation, info)
 	}
 	return oc
 }
 
-func (oc *Opts) commit() {
-	for _, c := range oc.committers {
-		c.commit()
+func (oc *Opts) addName(name string, oh optHandler, info *optInfo) *Opts {
+	if _, ok := oc.handlers[name]; ok {
+		oc.setError(&DuplicateOptionError{name})
+	} else {
+		oc.handlers[name] = oh
+		oc.infos[name] = info
 	}
+	return oc
 }
 
-func isNegatable(left, right string) bool {
-	// Overthinking this :-).
-	if len(left) >= 2 && len(right) >= 2 {
-		if left[0:2] != "no" && right[0:2] != "no" {
-			return false
+// Returns every option once, sorted by name.
+func (oc *Opts) options() []*optInfo {
+	infos := make([]*optInfo, 0, len(oc.infos))
+	for name, info := range oc.infos {
+		if name == info.name {
+			infos = append(infos, info)
 		}
 	}
-	return left == negatedName(right) || right == negatedName(left)
+	slices.SortFunc(infos, func(a, b *optInfo) int {
+		return strings.Compare(a.name, b.name)
+	})
+	return infos
 }
 
 type namedHandler struct {
 	name    string
 	handler optHandler
+	info    *optInfo
 }
 
 func (h namedHandler) checkConflict(o namedHandler) bool {
-	// Common case, because conflicts aren't dynamic.
-	if !h.handler.checkConflict(o.handler) {
-		return false
-	}
-
-	// If these are a negatabe pair, there is no conflict.
-	// TODO: I don't like this happening here.
-	if isNegatable(h.name, o.name) {
+	// Aliases and negated forms of an option share its pointer, so there
+	// is no conflict.
+	if h.info == o.info {
 		return false
 	}
-	return true
+	return h.handler.checkConflict(o.handler)
 }
 
 func (oc *Opts) checkConflicts() error {
 	handlers := make([]namedHandler, 0, len(oc.handlers))
 	for k, v := range oc.handlers {
-		handlers = append(handlers, namedHandler{k, v})
+		handlers = append(handlers, namedHandler{k, v, oc.infos[k]})
 	}
 
 	// TODO: The N^2 is concerning.  One solution would be to have each
@@ -100,7 +299,7 @@ func (oc *Opts) checkConflicts() error {
 	for i := len(handlers) - 1; i > 0; i-- {
 		for j := 0; j < i; j++ {
 			if handlers[i].checkConflict(handlers[j]) {
-				return fmt.Errorf("%s and %s use the same pointer", handlers[i].name, handlers[j].name)
+				return &PointerConflictError{handlers[i].name, handlers[j].name}
 			}
 		}
 	}
@@ -114,9 +313,63 @@ func (oc *Opts) checkConflicts() error {
 // Multiple same-named options is an error.
 // Options referring to the same pointer output is an error.
 // It is an error for args to contain a pattern which looks like an option but
-// which is not defined.
+// which is not defined.  This includes every character of a bundle of short
+// options like -vvx.
 // It is an error for a non-optional option to have no value.
+// It is an error for a Required() option to be missing, or for a group of
+// options to break its constraint.
+//
+// Errors in the arguments are returned as [UnknownOptionError],
+// [AmbiguousOptionError], [MissingArgumentError] or [InvalidValueError], and
+// errors building the Opts as [DuplicateOptionError] or
+// [PointerConflictError], for use with [errors.As].  Bad values from the
+// environment or config files are also [InvalidValueError].
+//
+// If subcommands were added with Command(), the first argument after the
+// options selects a subcommand, whose options are processed from the
+// following arguments.  The returned args are what is left after the
+// subcommand's options.
 func (oc *Opts) ProcessArgs(args []string) ([]string, error) {
+	r, err := oc.process(args)
+	if err != nil {
+		return args, err
+	}
+
+	// If we made it here without an error, commit the parsed arguments
+	// to their pointers.
+	r.Commit()
+	oc.store(r)
+	return r.args, nil
+}
+
+// Remember the result of a ProcessArgs() in the Opts it used, for
+// SelectedCommand(), IsSet() and friends.  Subcommands which were not used
+// forget the last result, and config files from LoadConfig() are used up.
+func (oc *Opts) store(r *Result) {
+	oc.forget()
+	for i, o := range r.chain {
+		if i < len(r.commands) {
+			o.selected = r.commands[i]
+		}
+		o.stored = r.stored
+	}
+}
+
+// Clear the result of the last ProcessArgs() and the loaded config files
+// from oc and its subcommands.
+func (oc *Opts) forget() {
+	oc.selected = ""
+	oc.stored = nil
+	oc.loaded = nil
+	for _, cmd := range oc.commands {
+		cmd.opts.forget()
+	}
+}
+
+// Parse args into committers in ps, without committing them.  Returns the
+// arguments left over.  base is the index of args[0] in the arguments to
+// ProcessArgs(), for errors.
+func (oc *Opts) parse(ps *optParse, args []string, base int) ([]string, error) {
 	// Return any errors in construction.
 	if oc.err != nil {
 		return args, oc.err
@@ -126,69 +379,264 @@ func (oc *Opts) ProcessArgs(args []string) ([]string, error) {
 	if err := oc.checkConflicts(); err != nil {
 		return args, err
 	}
+	ps.chain = append(ps.chain, oc)
+
+	// Arguments skipped over in permute or pass-through mode, to be
+	// returned in their original order.
+	var kept []string
+
+	// Errors so far, if collecting them, see CollectErrors().
+	var errs []error
+	collect := oc.collecting()
 
 	rest := args
 	for len(rest) > 0 {
-		name, ok := strings.CutPrefix(rest[0], "--")
-		if !ok {
+		arg, index := rest[0], base+len(args)-len(rest)
+		if rest[0] == "--" {
+			rest = rest[1:]
 			break
 		}
-		rest = rest[1:]
-		if len(name) == 0 {
+
+		if name, noneOrOne, ok := splitLong(rest[0]); ok {
+			h, info, name, err := oc.resolve(name)
+			if err != nil && oc.passThrough {
+				kept = append(kept, rest[0])
+				rest = rest[1:]
+				continue
+			} else if err != nil {
+				if !collect {
+					return args, atArg(err, arg, index)
+				}
+				errs = append(errs, atArg(err, arg, index))
+				rest = rest[1:]
+				continue
+			}
+
+			start, from := len(ps.queued[oc]), rest
+			rest, err = oc.handleOption(ps, name, info, h, noneOrOne, rest[1:])
+			oc.setArgOrigins(ps, start, index, from[:len(from)-len(rest)])
+			if err != nil {
+				if !collect {
+					return args, atArg(err, arg, index)
+				}
+				errs = append(errs, atArg(err, arg, index))
+			}
+			continue
+		}
+
+		// Without short options, arguments like -1 are not options.
+		if bundle, ok := strings.CutPrefix(rest[0], "-"); ok && len(bundle) > 0 && oc.hasShorts() {
+			if oc.passThrough && !oc.knownBundle(bundle) {
+				kept = append(kept, rest[0])
+				rest = rest[1:]
+				continue
+			}
+			start, from := len(ps.queued[oc]), rest
+			rest = rest[1:]
+
+			var err error
+			rest, err = oc.handleBundle(ps, bundle, rest)
+			oc.setArgOrigins(ps, start, index, from[:len(from)-len(rest)])
+			if err != nil {
+				if !collect {
+					return args, atArg(err, arg, index)
+				}
+				errs = append(errs, atArg(err, arg, index))
+			}
+			continue
+		}
+
+		// Subcommands need the first non-option argument, so they
+		// always stop here.
+		if !oc.permute || len(oc.commands) > 0 {
 			break
 		}
+		kept = append(kept, rest[0])
+		rest = rest[1:]
+	}
 
-		noneOrOne := strings.SplitN(name, "=", 2)
-		// noneOrOne can't be empty?  But if it were, don't [0].
-		if len(noneOrOne) > 0 {
-			name = noneOrOne[0]
-			noneOrOne = noneOrOne[1:]
+	if len(oc.commands) > 0 {
+		cmd, err := oc.selectCommand(rest)
+		if err != nil {
+			return args, joinErrors(append(errs, err))
 		}
+		ps.commands = append(ps.commands, rest[0])
+		subRest, err := cmd.opts.parse(ps, rest[1:], base+len(args)-len(rest)+1)
+		if err != nil {
+			return args, joinErrors(append(errs, err))
+		}
+		rest = subRest
+	}
+	if len(errs) > 0 {
+		return args, joinErrors(errs)
+	}
+	return append(kept, rest...), nil
+}
+
+// Record the origin of the committers queued for oc since start, which came
+// from the arguments used, starting at index.
+func (oc *Opts) setArgOrigins(ps *optParse, start int, index int, used []string) {
+	raw := strings.Join(used, " ")
+	queued := ps.queued[oc]
+	for i := start; i < len(queued); i++ {
+		queued[i].origin = Origin{Kind: FromArgs, Index: index, Raw: raw}
+	}
+}
+
+// Split a long option like --name=value into name and, if present, value.
+// Returns false if arg is not a long option.
+func splitLong(arg string) (string, []string, bool) {
+	name, ok := strings.CutPrefix(arg, "--")
+	if !ok {
+		return "", nil, false
+	}
+	noneOrOne := strings.SplitN(name, "=", 2)
+	// noneOrOne can't be empty?  But if it were, don't [0].
+	if len(noneOrOne) > 0 {
+		name = noneOrOne[0]
+		noneOrOne = noneOrOne[1:]
+	}
+	return name, noneOrOne, true
+}
+
+// Returns true if arg should not be taken as the value of an optional
+// option.
+func (oc *Opts) looksLikeOption(arg string) bool {
+	if strings.HasPrefix(arg, "--") {
+		return true
+	}
+	if bundle, ok := strings.CutPrefix(arg, "-"); ok && len(bundle) > 0 {
+		_, _, ok := oc.lookupShort(string([]rune(bundle)[0]))
+		return ok
+	}
+	return false
+}
+
+// Work out the argument for an option with handler h, consuming it from
+// rest if needed.  inline is the argument if it was attached to the option
+// itself, like --name=value or -nvalue.  Returns the argument, if any, and
+// the remainder of rest.  wanted is true if the argument would be the next
+// of rest, but rest has run out.  This is shared by ProcessArgs() and
+// completions, so they agree on which words are values.
+func (oc *Opts) optionArg(h optHandler, inline []string, rest []string) ([]string, []string, bool) {
+	// TODO: Provide a way for handlers to vet the next arg.
+	// For instance, --optional-integer followed by non-integer
+	// text could yield the default and end processing.
+
+	if h.getType() == optNoArg || len(inline) > 0 {
+		return inline, rest, false
+	} else if len(rest) < 1 {
+		return nil, rest, true
+	} else if h.getType() == optOptionalArg && oc.looksLikeOption(rest[0]) {
+		// Nothing, next arg looks flag-like
+		return nil, rest, false
+	}
+	// This will treat the next arg as a value unconditionally, even if
+	// it looks like an option.
+	return rest[0:1], rest[1:], false
+}
 
-		h, ok := oc.handlers[name]
+// Run h for the option called name, consuming the option's argument from
+// rest if needed, see optionArg().  Returns the remainder of rest.
+func (oc *Opts) handleOption(ps *optParse, name string, info *optInfo, h optHandler, inline []string, rest []string) ([]string, error) {
+	inline, rest, wanted := oc.optionArg(h, inline, rest)
+	if wanted && h.getType() == optRequiredArg {
+		return rest, &MissingArgumentError{Name: name}
+	}
+
+	value := ""
+	if len(inline) > 0 {
+		value = inline[0]
+	}
+	c, err := h.handle(inline)
+	if err != nil {
+		return rest, &InvalidValueError{Name: name, Value: value, Err: err}
+	}
+	// Boolean flags record what they store, so --noverbose can be told
+	// from --verbose.
+	if sc, ok := c.(optSimpleCommitter[bool]); ok && len(inline) == 0 {
+		value = strconv.FormatBool(sc.value)
+	}
+	ps.queued[oc] = append(ps.queued[oc], optPending{info, c, value, Origin{Kind: FromArgs}})
+	return rest, nil
+}
+
+// Returns true if handleBundle() would not hit an unknown option.
+func (oc *Opts) knownBundle(bundle string) bool {
+	for _, ch := range bundle {
+		h, _, ok := oc.lookupShort(string(ch))
 		if !ok {
-			return args, fmt.Errorf("arg %s not recognized", name)
+			return false
 		}
+		if h.getType() != optNoArg {
+			break
+		}
+	}
+	return true
+}
 
-		// TODO: Provide a way for handlers to vet the next arg.
-		// For instance, --optional-integer followed by non-integer
-		// text could yield the default and end processing.
+// Process a bundle of short options, like -vvx.  Options without arguments
+// can be stacked, the first option which takes an argument consumes the rest
+// of the bundle as its value, like -l24.  If nothing is left in the bundle,
+// the value comes from rest, like -l 24.
+func (oc *Opts) handleBundle(ps *optParse, bundle string, rest []string) ([]string, error) {
+	// Errors so far, if collecting them, see CollectErrors().
+	var errs []error
 
+	chars := []rune(bundle)
+	for i, ch := range chars {
+		flag := "-" + string(ch)
+		h, info, ok := oc.lookupShort(string(ch))
+		if !ok {
+			err := &UnknownOptionError{Name: flag}
+			if !oc.collecting() {
+				return rest, err
+			}
+			errs = append(errs, err)
+			continue
+		}
 		if h.getType() == optNoArg {
-			// Nothing
-		} else if len(noneOrOne) > 0 {
-			// Nothing, already have an arg
-		} else if len(rest) < 1 {
-			if h.getType() == optRequiredArg {
-				return args, fmt.Errorf("arg %s missing required argument", name)
+			var err error
+			rest, err = oc.handleOption(ps, flag, info, h, nil, rest)
+			if err != nil {
+				return rest, joinErrors(append(errs, err))
 			}
-			// For optional, no more args is fine
-		} else if h.getType() == optOptionalArg && strings.HasPrefix(rest[0], "--") {
-			// Nothing, next arg looks flag-like
-		} else {
-			// This will treat the next arg as a value
-			// unconditionally, even if it looks like an
-			// option.
-			noneOrOne = rest[0:1]
-			rest = rest[1:]
+			continue
 		}
 
-		c, err := h.handle(noneOrOne)
-		if err != nil {
-			return args, err
+		var inline []string
+		if i+1 < len(chars) {
+			inline = []string{string(chars[i+1:])}
 		}
-		oc.committers = append(oc.committers, c)
+		rest, err := oc.handleOption(ps, flag, info, h, inline, rest)
+		return rest, joinErrors(append(errs, err))
 	}
+	return rest, joinErrors(errs)
+}
 
-	// If we made it here without an error, commit the parsed arguments
-	// to their pointers.
-	oc.commit()
-	return rest, nil
+// Like Getopt::Long's GetOptionsFromString, split s into arguments and pass
+// them to [ProcessArgs], for options from places like an environment
+// variable.  s is split like a POSIX shell would, with single and double
+// quotes and backslash escapes, but no expansions.  Unterminated quotes are
+// an error.
+func (oc *Opts) ProcessString(s string) ([]string, error) {
+	args, err := splitWords(s)
+	if err != nil {
+		return nil, err
+	}
+	return oc.ProcessArgs(args)
 }
 
 // Wrapper to pass [os.Args][1:] to [ProcessArgs].  On success [os.Args] is
 // updated with the returned args.
+//
+// If the first argument is [CompletionArg], completions are written to
+// [os.Stdout] instead, and the program exits.  See [Opts.WriteCompletions].
 func (oc *Opts) ProcessOSArgs() error {
+	if oc.completeOSArgs() {
+		return nil
+	}
+
 	ret, err := oc.ProcessArgs(os.Args[1:])
 	if err != nil {
 		return err
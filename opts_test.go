@@ -162,6 +162,48 @@ func TestOSArgsError(t *testing.T) {
 	assert.Equal(t, []string{"command", "--string"}, os.Args)
 }
 
+func TestProcessString(t *testing.T) {
+	{
+		wantTrue := false
+		wantWords := "calm"
+		wantArray := []string{}
+		ret, err := NewOpts().
+			SimpleOption("verbose", &wantTrue).
+			StringOption("name", &wantWords).
+			StringArrayOption("file", &wantArray).
+			ProcessString(`--verbose --name 'two words' --file="a \"b\"" --file=c\ d left "right side"`)
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.Equal(t, "two words", wantWords)
+			assert.Equal(t, []string{`a "b"`, "c d"}, wantArray)
+			assert.Equal(t, []string{"left", "right side"}, ret)
+		}
+	}
+
+	{
+		stayFalse := false
+		ret, err := NewOpts().
+			SimpleOption("verbose", &stayFalse).
+			ProcessString(`--verbose --name "two words`)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "unterminated double quote")
+		}
+		assert.False(t, stayFalse)
+		assert.Nil(t, ret)
+	}
+
+	{
+		stayCalm := "calm"
+		ret, err := NewOpts().
+			StringOption("name", &stayCalm).
+			ProcessString("--name")
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "missing")
+		}
+		assert.Equal(t, []string{"--name"}, ret)
+	}
+}
+
 func ExampleNewOpts() {
 	args := []string{
 		"--files=hello.world", "--length", "10", "--verbose", "rest",
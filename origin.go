@@ -0,0 +1,125 @@
+package opts
+
+import (
+	"fmt"
+)
+
+// SourceKind says which kind of source an option's value came from.
+type SourceKind int
+
+const (
+	// The value the option pointer had before ProcessArgs().
+	FromDefault SourceKind = iota
+
+	// A config file, from LoadConfig() or Sources().
+	FromConfig
+
+	// An environment variable, see Env() and EnvPrefix().
+	FromEnv
+
+	// The arguments to ProcessArgs().
+	FromArgs
+)
+
+func (k SourceKind) String() string {
+	switch k {
+	case FromDefault:
+		return "default"
+	case FromConfig:
+		return "config"
+	case FromEnv:
+		return "env"
+	case FromArgs:
+		return "args"
+	default:
+		return fmt.Sprintf("SourceKind(%d)", int(k))
+	}
+}
+
+// Origin says where a value stored by ProcessArgs() came from.
+type Origin struct {
+	Kind SourceKind
+
+	// For FromArgs, the index of the option in the arguments.
+	Index int
+
+	// For FromConfig, the name of the file and the line of the value, if
+	// they are known.  Files from LoadConfig() have no name.
+	File string
+	Line int
+
+	// For FromEnv, the name of the variable.
+	Env string
+
+	// The text the value was parsed from.  For FromArgs this is the
+	// arguments used, like "--length 11".  For FromEnv it is the whole
+	// variable, even for array options.  For FromConfig it is the value
+	// from the file, with key= added for map options.
+	Raw string
+}
+
+func (o Origin) String() string {
+	switch o.Kind {
+	case FromArgs:
+		return fmt.Sprintf("argument %d", o.Index)
+	case FromEnv:
+		return "environment variable " + o.Env
+	case FromConfig:
+		switch {
+		case o.File != "" && o.Line > 0:
+			return fmt.Sprintf("%s:%d", o.File, o.Line)
+		case o.File != "":
+			return o.File
+		case o.Line > 0:
+			return fmt.Sprintf("config line %d", o.Line)
+		default:
+			return "config"
+		}
+	default:
+		return o.Kind.String()
+	}
+}
+
+// Returns where the value of option name, which can be any of its names,
+// came from in the last successful ProcessArgs().  Options which were not
+// given have Kind FromDefault.  For array options, and options given more
+// than once, this is the last value stored.  Returns false if there is no
+// such option.
+//
+// For options of subcommands, use the subcommand's Opts.
+func (oc *Opts) Origin(name string) (Origin, bool) {
+	_, info, ok := oc.lookup(name)
+	if !ok {
+		return Origin{}, false
+	}
+	return lastOrigin(oc.stored[info]), true
+}
+
+// Returns the origin of the last of stored, or FromDefault if it is empty.
+func lastOrigin(stored []optPending) Origin {
+	if len(stored) == 0 {
+		return Origin{Kind: FromDefault}
+	}
+	return stored[len(stored)-1].origin
+}
+
+// Returns where every value stored by the last successful ProcessArgs()
+// came from, by option name, in the order they were stored.  Options which
+// were not given are left out.
+func (oc *Opts) Provenance() map[string][]Origin {
+	return provenance(oc.visibleNames(), oc.stored)
+}
+
+// Returns the origins of the values in stored for the options in names.
+func provenance(names map[string]*optInfo, stored map[*optInfo][]optPending) map[string][]Origin {
+	provenance := make(map[string][]Origin)
+	for name, info := range names {
+		if name != info.name {
+			continue
+		}
+		for _, p := range stored[info] {
+			provenance[info.name] = append(provenance[info.name], p.origin)
+		}
+	}
+	return provenance
+}
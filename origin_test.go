@@ -0,0 +1,160 @@
+package opts
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrigin(t *testing.T) {
+	t.Setenv("TEST_OPTS_TAGS", "a,b")
+	fsys := fstest.MapFS{
+		"tool.yaml": {Data: []byte("timeout: 30\nlabel:\n  app: web\n")},
+	}
+
+	var (
+		timeout = 10
+		retries = 3
+		verbose = 0
+		length  = 24
+		tags    = []string{}
+		labels  = map[string]string{}
+		name    = "calm"
+	)
+	oc := NewOpts().
+		Sources(ConfigFile(fsys, "tool.yaml", ConfigYAML)).
+		IntOption("timeout", &timeout).
+		IntOption("retries", &retries).
+		CountingOption("verbose", &verbose).Short("v").
+		IntOption("length", &length).Short("l").AKA("len").
+		StringArrayOption("tag", &tags).Env("TEST_OPTS_TAGS").
+		StringMapOption("label", &labels).
+		StringOption("name", &name)
+	require.Nil(t, oc.LoadConfig(strings.NewReader("name = chaos\n"), ConfigINI))
+
+	// Nothing is known until ProcessArgs().
+	origin, ok := oc.Origin("timeout")
+	if assert.True(t, ok) {
+		assert.Equal(t, FromDefault, origin.Kind)
+	}
+
+	_, err := oc.ProcessArgs([]string{"-vl", "11", "--verbose", "left"})
+	require.Nil(t, err)
+
+	origin, ok = oc.Origin("timeout")
+	if assert.True(t, ok) {
+		assert.Equal(t, Origin{Kind: FromConfig, File: "tool.yaml", Line: 1, Raw: "30"}, origin)
+		assert.Equal(t, "tool.yaml:1", origin.String())
+	}
+
+	origin, ok = oc.Origin("retries")
+	if assert.True(t, ok) {
+		assert.Equal(t, FromDefault, origin.Kind)
+		assert.Equal(t, "default", origin.String())
+	}
+
+	origin, ok = oc.Origin("len")
+	if assert.True(t, ok) {
+		assert.Equal(t, Origin{Kind: FromArgs, Index: 0, Raw: "-vl 11"}, origin)
+		assert.Equal(t, "argument 0", origin.String())
+	}
+
+	origin, ok = oc.Origin("tag")
+	if assert.True(t, ok) {
+		assert.Equal(t, Origin{Kind: FromEnv, Env: "TEST_OPTS_TAGS", Raw: "a,b"}, origin)
+		assert.Equal(t, "environment variable TEST_OPTS_TAGS", origin.String())
+	}
+
+	origin, ok = oc.Origin("name")
+	if assert.True(t, ok) {
+		assert.Equal(t, Origin{Kind: FromConfig, Line: 1, Raw: "chaos"}, origin)
+		assert.Equal(t, "config line 1", origin.String())
+	}
+
+	_, ok = oc.Origin("missing")
+	assert.False(t, ok)
+
+	assert.Equal(t, map[string][]Origin{
+		"timeout": {{Kind: FromConfig, File: "tool.yaml", Line: 1, Raw: "30"}},
+		"label":   {{Kind: FromConfig, File: "tool.yaml", Line: 3, Raw: "app=web"}},
+		"verbose": {
+			{Kind: FromArgs, Index: 0, Raw: "-vl 11"},
+			{Kind: FromArgs, Index: 2, Raw: "--verbose"},
+		},
+		"length": {{Kind: FromArgs, Index: 0, Raw: "-vl 11"}},
+		"tag": {
+			{Kind: FromEnv, Env: "TEST_OPTS_TAGS", Raw: "a,b"},
+			{Kind: FromEnv, Env: "TEST_OPTS_TAGS", Raw: "a,b"},
+		},
+		"name": {{Kind: FromConfig, Line: 1, Raw: "chaos"}},
+	}, oc.Provenance())
+}
+
+func TestOriginCommand(t *testing.T) {
+	verbose := false
+	replicas := 1
+	deploy := NewOpts().IntOption("replicas", &replicas)
+	oc := NewOpts().
+		SimpleOption("verbose", &verbose).Persistent().
+		Command("deploy", deploy, nil)
+
+	_, err := oc.ProcessArgs([]string{"deploy", "--replicas=3", "--verbose"})
+	require.Nil(t, err)
+
+	origin, ok := deploy.Origin("replicas")
+	if assert.True(t, ok) {
+		assert.Equal(t, Origin{Kind: FromArgs, Index: 1, Raw: "--replicas=3"}, origin)
+	}
+	origin, ok = deploy.Origin("verbose")
+	if assert.True(t, ok) {
+		assert.Equal(t, Origin{Kind: FromArgs, Index: 2, Raw: "--verbose"}, origin)
+	}
+	origin, ok = oc.Origin("verbose")
+	if assert.True(t, ok) {
+		assert.Equal(t, FromArgs, origin.Kind)
+	}
+	_, ok = oc.Origin("replicas")
+	assert.False(t, ok)
+
+	// A failed ProcessArgs() leaves the origins alone.
+	_, err = oc.ProcessArgs([]string{"deploy", "--replicas=x"})
+	require.NotNil(t, err)
+	origin, _ = deploy.Origin("replicas")
+	assert.Equal(t, FromArgs, origin.Kind)
+}
+
+func TestOriginJSON(t *testing.T) {
+	files := []string{}
+	replicas := 1
+	deploy := NewOpts().IntOption("replicas", &replicas)
+	oc := NewOpts().
+		StringArrayOption("file", &files).
+		Command("deploy", deploy, nil)
+	fsys := fstest.MapFS{
+		"tool.json": {Data: []byte(`{
+	"file": [
+		"a",
+		"b"
+	],
+	"deploy": {"replicas": 3}
+}
+`)},
+	}
+	oc.Sources(ConfigFile(fsys, "tool.json", ConfigJSON))
+	_, err := oc.ProcessArgs([]string{"deploy"})
+	require.Nil(t, err)
+
+	assert.Equal(t, map[string][]Origin{
+		"file": {
+			{Kind: FromConfig, File: "tool.json", Line: 3, Raw: "a"},
+			{Kind: FromConfig, File: "tool.json", Line: 4, Raw: "b"},
+		},
+	}, oc.Provenance())
+	origin, ok := deploy.Origin("replicas")
+	if assert.True(t, ok) {
+		assert.Equal(t, "tool.json:6", origin.String())
+	}
+}
@@ -0,0 +1,196 @@
+package opts
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// A Parser parses arguments for an Opts, without changing it.  Each call to
+// Parse() has its own Result, so a Parser can be used again and again, and
+// by several goroutines at once.  See Compile().
+type Parser struct {
+	opts *Opts
+}
+
+// Returns a Parser for oc, or the first error from building oc or its
+// subcommands.  oc must not be changed after this, and LoadConfig() must not
+// be called on it, as the Parser shares it.
+func (oc *Opts) Compile() (*Parser, error) {
+	if err := oc.checkTree(); err != nil {
+		return nil, err
+	}
+	return &Parser{oc}, nil
+}
+
+// Returns the first error from building oc or any of its subcommands,
+// including options which share a pointer.
+func (oc *Opts) checkTree() error {
+	if oc.err != nil {
+		return oc.err
+	}
+	if err := oc.checkConflicts(); err != nil {
+		return err
+	}
+	for _, name := range oc.commandNames() {
+		if err := oc.commands[name].opts.checkTree(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse args like ProcessArgs(), but return the values in a Result rather
+// than storing them.  Nothing is stored until Result.Commit().
+func (p *Parser) Parse(args []string) (*Result, error) {
+	return p.opts.process(args)
+}
+
+// Parse args like Parse(), commit the Result, then call the run function of
+// the selected subcommand, like Opts.Run().
+func (p *Parser) Run(args []string) error {
+	if len(p.opts.commands) == 0 {
+		return fmt.Errorf("no commands to run")
+	}
+	r, err := p.Parse(args)
+	if err != nil {
+		return err
+	}
+	r.Commit()
+	return r.run()
+}
+
+// A Result holds the values parsed by one call to Parser.Parse().
+type Result struct {
+	// The Opts used, which is the top-level Opts followed by any
+	// selected subcommands, and the names of the subcommands.
+	chain    []*Opts
+	commands []string
+
+	// The arguments left over.
+	args []string
+
+	// The committers from every layer, in the order to commit them.
+	pending []optPending
+
+	// pending, by option.
+	stored map[*optInfo][]optPending
+}
+
+// Process args into a Result, without storing anything in oc or the option
+// pointers.
+func (oc *Opts) process(args []string) (*Result, error) {
+	if oc.err != nil {
+		return nil, oc.err
+	}
+	expanded, err := oc.expandArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	ps := &optParse{queued: make(map[*Opts][]optPending)}
+	rest, err := oc.parse(ps, expanded, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Options not on the command line fall back to the environment, then
+	// to config files.
+	pending, err := mergeLayers(ps)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRequired(ps.chain, pending); err != nil {
+		return nil, err
+	}
+	if err := checkGroups(ps.chain, pending); err != nil {
+		return nil, err
+	}
+
+	stored := make(map[*optInfo][]optPending)
+	for _, p := range pending {
+		stored[p.info] = append(stored[p.info], p)
+	}
+	return &Result{ps.chain, ps.commands, rest, pending, stored}, nil
+}
+
+// Returns the arguments left over, like ProcessArgs().
+func (r *Result) Args() []string {
+	return r.args
+}
+
+// Returns the names of the selected subcommands, outermost first, like
+// ["deploy", "start"].
+func (r *Result) Commands() []string {
+	return slices.Clone(r.commands)
+}
+
+// Store the values into the option pointers.  The pointers are shared by
+// every Result from the Parser, so Commit() must not be called from several
+// goroutines at once, and array options get the values appended again if it
+// is called twice.
+func (r *Result) Commit() {
+	for _, p := range r.pending {
+		p.committer.commit()
+	}
+}
+
+// Returns the option for name, from the innermost subcommand which can see
+// it.
+func (r *Result) lookup(name string) (*optInfo, bool) {
+	for i := len(r.chain) - 1; i >= 0; i-- {
+		if _, info, ok := r.chain[i].lookup(name); ok {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// Returns every flag name lookup() can find.
+func (r *Result) visibleNames() map[string]*optInfo {
+	names := make(map[string]*optInfo)
+	for _, oc := range r.chain {
+		maps.Copy(names, oc.visibleNames())
+	}
+	return names
+}
+
+// Like Opts.IsSet(), for the options of the top-level Opts and the selected
+// subcommands.
+func (r *Result) IsSet(name string) bool {
+	info, ok := r.lookup(name)
+	return ok && len(r.stored[info]) > 0
+}
+
+// Like Opts.Origin(), for the options of the top-level Opts and the selected
+// subcommands.
+func (r *Result) Origin(name string) (Origin, bool) {
+	info, ok := r.lookup(name)
+	if !ok {
+		return Origin{}, false
+	}
+	return lastOrigin(r.stored[info]), true
+}
+
+// Like Opts.Provenance(), for the options of the top-level Opts and the
+// selected subcommands.
+func (r *Result) Provenance() map[string][]Origin {
+	return provenance(r.visibleNames(), r.stored)
+}
+
+// Like Opts.Visit(), for the options of the top-level Opts and the selected
+// subcommands.
+func (r *Result) Visit(fn func(name string, rawValues []string)) {
+	visit(r.visibleNames(), r.stored, fn)
+}
+
+// Call the run function of the innermost selected subcommand with the
+// arguments left over.
+func (r *Result) run() error {
+	name := r.commands[len(r.commands)-1]
+	cmd := r.chain[len(r.chain)-2].commands[name]
+	if cmd.run == nil {
+		return fmt.Errorf("command %s cannot be run", name)
+	}
+	return cmd.run(r.args)
+}
@@ -0,0 +1,218 @@
+package opts
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessArgsTwice(t *testing.T) {
+	verbose := 0
+	name := "calm"
+	files := []string{}
+	force := false
+	oc := NewOpts().
+		CountingOption("verbose", &verbose).Short("v").
+		StringOption("name", &name).
+		StringArrayOption("file", &files).
+		Command("deploy", NewOpts().SimpleOption("force", &force), nil).
+		Command("status", NewOpts(), nil)
+
+	rest, err := oc.ProcessArgs([]string{"-vv", "--name=loud", "--file=a", "deploy", "--force", "x"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"x"}, rest)
+	assert.Equal(t, 2, verbose)
+	assert.Equal(t, "loud", name)
+	assert.Equal(t, []string{"a"}, files)
+	assert.True(t, force)
+	assert.Equal(t, "deploy", oc.SelectedCommand())
+
+	// Nothing from the first call is applied again.
+	verbose, name, files, force = 0, "calm", []string{}, false
+	rest, err = oc.ProcessArgs([]string{"--file=b", "status"})
+	require.Nil(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, 0, verbose)
+	assert.Equal(t, "calm", name)
+	assert.Equal(t, []string{"b"}, files)
+	assert.False(t, force)
+	assert.Equal(t, "status", oc.SelectedCommand())
+	assert.False(t, oc.IsSet("verbose"))
+	assert.True(t, oc.IsSet("file"))
+
+	// Nor from a call which failed.
+	_, err = oc.ProcessArgs([]string{"-v", "--missing", "status"})
+	assert.NotNil(t, err)
+	_, err = oc.ProcessArgs([]string{"status"})
+	require.Nil(t, err)
+	assert.Equal(t, 0, verbose)
+}
+
+func TestProcessArgsForgets(t *testing.T) {
+	force := false
+	now := false
+	start := NewOpts().SimpleOption("now", &now)
+	deploy := NewOpts().
+		SimpleOption("force", &force).
+		Command("start", start, nil).
+		Command("stop", NewOpts(), nil)
+	oc := NewOpts().
+		Command("deploy", deploy, nil).
+		Command("status", NewOpts(), nil)
+
+	_, err := oc.ProcessArgs([]string{"deploy", "--force", "start", "--now"})
+	require.Nil(t, err)
+	assert.Equal(t, "start", deploy.SelectedCommand())
+	assert.True(t, deploy.IsSet("force"))
+	assert.True(t, start.IsSet("now"))
+
+	// Subcommands which were not used forget the last call.
+	_, err = oc.ProcessArgs([]string{"status"})
+	require.Nil(t, err)
+	assert.Equal(t, "status", oc.SelectedCommand())
+	assert.Equal(t, "", deploy.SelectedCommand())
+	assert.False(t, deploy.IsSet("force"))
+	assert.False(t, start.IsSet("now"))
+	origin, ok := start.Origin("now")
+	assert.True(t, ok)
+	assert.Equal(t, FromDefault, origin.Kind)
+	assert.Empty(t, deploy.Provenance())
+}
+
+func TestParser(t *testing.T) {
+	verbose := 0
+	length := 24
+	force := false
+	oc := NewOpts().
+		CountingOption("verbose", &verbose).Short("v").Persistent().
+		IntOption("length", &length).
+		Command("deploy", NewOpts().SimpleOption("force", &force), nil)
+	p, err := oc.Compile()
+	require.Nil(t, err)
+
+	r, err := p.Parse([]string{"--length=11", "deploy", "-v", "--force", "x"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"x"}, r.Args())
+	assert.Equal(t, []string{"deploy"}, r.Commands())
+	assert.True(t, r.IsSet("length"))
+	assert.True(t, r.IsSet("force"))
+	assert.True(t, r.IsSet("verbose"))
+	assert.False(t, r.IsSet("missing"))
+	origin, ok := r.Origin("force")
+	assert.True(t, ok)
+	assert.Equal(t, Origin{Kind: FromArgs, Index: 3, Raw: "--force"}, origin)
+	assert.Equal(t, map[string][]Origin{
+		"length":  {{Kind: FromArgs, Index: 0, Raw: "--length=11"}},
+		"verbose": {{Kind: FromArgs, Index: 2, Raw: "-v"}},
+		"force":   {{Kind: FromArgs, Index: 3, Raw: "--force"}},
+	}, r.Provenance())
+
+	// Nothing is stored until Commit().
+	assert.Equal(t, 24, length)
+	assert.False(t, force)
+	assert.Equal(t, "", oc.SelectedCommand())
+	assert.False(t, oc.IsSet("length"))
+	r.Commit()
+	assert.Equal(t, 11, length)
+	assert.True(t, force)
+	assert.Equal(t, 1, verbose)
+
+	_, err = p.Parse([]string{"--length=eleven", "deploy"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "length")
+
+	// Errors building the Opts come from Compile().
+	{
+		_, err := NewOpts().
+			Command("deploy", NewOpts().
+				IntOption("length", &length).
+				IntOption("size", &length), nil).
+			Compile()
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "same pointer")
+	}
+}
+
+func TestParserRun(t *testing.T) {
+	ran := []string{}
+	oc := NewOpts().
+		Command("deploy", NewOpts(), func(args []string) error {
+			ran = append(ran, args...)
+			return nil
+		}).
+		Command("status", NewOpts(), nil)
+	p, err := oc.Compile()
+	require.Nil(t, err)
+
+	require.Nil(t, p.Run([]string{"deploy", "a", "b"}))
+	assert.Equal(t, []string{"a", "b"}, ran)
+
+	err = p.Run([]string{"status"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cannot be run")
+}
+
+func TestParserConcurrent(t *testing.T) {
+	verbose := 0
+	length := 24
+	files := []string{}
+	force := false
+	p, err := NewOpts().
+		CountingOption("verbose", &verbose).Short("v").Persistent().
+		IntOption("length", &length).
+		StringArrayOption("file", &files).
+		Command("deploy", NewOpts().SimpleOption("force", &force), nil).
+		Command("status", NewOpts(), nil).
+		Compile()
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 32 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			length := fmt.Sprint(i)
+			args := []string{"--length", length}
+			for range i % 4 {
+				args = append(args, "-v")
+			}
+			if i%2 == 0 {
+				args = append(args, "--file", length, "deploy", "--force", length)
+			} else {
+				args = append(args, "status")
+			}
+
+			r, err := p.Parse(args)
+			if !assert.Nil(t, err) {
+				return
+			}
+			values := map[string][]string{}
+			r.Visit(func(name string, rawValues []string) {
+				values[name] = rawValues
+			})
+			if i%2 == 0 {
+				assert.Equal(t, []string{"deploy"}, r.Commands())
+				assert.Equal(t, []string{length}, r.Args())
+				assert.Equal(t, []string{length}, values["file"])
+				assert.True(t, r.IsSet("force"))
+			} else {
+				assert.Equal(t, []string{"status"}, r.Commands())
+				assert.Empty(t, r.Args())
+				assert.False(t, r.IsSet("file"))
+			}
+			assert.Equal(t, []string{length}, values["length"])
+			assert.Equal(t, i%4 > 0, r.IsSet("verbose"))
+			assert.Len(t, values["verbose"], i%4)
+		}()
+	}
+	wg.Wait()
+
+	// The pointers were left alone.
+	assert.Equal(t, 0, verbose)
+	assert.Equal(t, 24, length)
+	assert.Equal(t, []string{}, files)
+	assert.False(t, force)
+}
@@ -0,0 +1,142 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassThrough(t *testing.T) {
+	{
+		wantTrue := false
+		wantEleven := 7
+		args := []string{
+			"--child-flag",
+			"--verbose",
+			"--child-value=x",
+			"--length", "11",
+			"left",
+			"--verbose",
+		}
+		ret, err := NewOpts().
+			PassThrough().
+			SimpleOption("verbose", &wantTrue).
+			IntOption("length", &wantEleven).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, []string{"--child-flag", "--child-value=x", "left", "--verbose"}, ret)
+		}
+	}
+
+	// A value not attached with = stops processing, like any other
+	// argument.
+	{
+		stayFalse := false
+		args := []string{
+			"--child-value", "x",
+			"--verbose",
+		}
+		ret, err := NewOpts().
+			PassThrough().
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.False(t, stayFalse)
+			assert.Equal(t, args, ret)
+		}
+	}
+
+	// Known options still fail as usual.
+	{
+		stayEleven := 7
+		args := []string{
+			"--child-flag",
+			"--length", "twelve",
+		}
+		ret, err := NewOpts().
+			PassThrough().
+			IntOption("length", &stayEleven).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "invalid syntax")
+		}
+		assert.Equal(t, 7, stayEleven)
+		assert.Equal(t, args, ret)
+	}
+
+	{
+		stayFalse := false
+		args := []string{
+			"--",
+			"--child-flag",
+		}
+		ret, err := NewOpts().
+			PassThrough().
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"--child-flag"}, ret)
+		}
+	}
+}
+
+func TestPassThroughShort(t *testing.T) {
+	wantOne := 0
+	wantLength := 7
+	args := []string{
+		"-vq",
+		"-v",
+		"-x",
+		"-l24",
+		"left",
+	}
+	ret, err := NewOpts().
+		PassThrough().
+		CountingOption("verbose", &wantOne).Short("v").
+		IntOption("length", &wantLength).Short("l").
+		ProcessArgs(args)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, wantOne)
+		assert.Equal(t, 24, wantLength)
+		assert.Equal(t, []string{"-vq", "-x", "left"}, ret)
+	}
+}
+
+func TestPassThroughPermute(t *testing.T) {
+	wantTrue := false
+	args := []string{
+		"file1",
+		"--child-flag",
+		"--verbose",
+		"file2",
+	}
+	ret, err := NewOpts().
+		PassThrough().
+		Permute().
+		SimpleOption("verbose", &wantTrue).
+		ProcessArgs(args)
+	if assert.Nil(t, err) {
+		assert.True(t, wantTrue)
+		assert.Equal(t, []string{"file1", "--child-flag", "file2"}, ret)
+	}
+}
+
+func TestPassThroughCommand(t *testing.T) {
+	wantTrue := false
+	wantForce := false
+	ret, err := NewOpts().
+		PassThrough().
+		SimpleOption("verbose", &wantTrue).
+		Command("deploy", NewOpts().
+			PassThrough().
+			SimpleOption("force", &wantForce),
+			nil).
+		ProcessArgs([]string{"--verbose", "--global", "deploy", "--local", "--force", "left"})
+	if assert.Nil(t, err) {
+		assert.True(t, wantTrue)
+		assert.True(t, wantForce)
+		assert.Equal(t, []string{"--global", "--local", "left"}, ret)
+	}
+}
@@ -0,0 +1,128 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermute(t *testing.T) {
+	{
+		wantTrue := false
+		wantEleven := 7
+		args := []string{
+			"file1",
+			"--verbose",
+			"file2",
+			"--length", "11",
+			"file3",
+		}
+		ret, err := NewOpts().
+			Permute().
+			SimpleOption("verbose", &wantTrue).
+			IntOption("length", &wantEleven).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, []string{"file1", "file2", "file3"}, ret)
+		}
+	}
+
+	// -- still ends option processing.
+	{
+		wantTrue := false
+		stayFalse := false
+		args := []string{
+			"file1",
+			"--want-true",
+			"--",
+			"file2",
+			"--stay-false",
+		}
+		ret, err := NewOpts().
+			Permute().
+			SimpleOption("want-true", &wantTrue).
+			SimpleOption("stay-false", &stayFalse).
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.False(t, stayFalse)
+			assert.Equal(t, []string{"file1", "file2", "--stay-false"}, ret)
+		}
+	}
+
+	{
+		wantTwo := 0
+		args := []string{
+			"-v",
+			"-",
+			"-v",
+		}
+		ret, err := NewOpts().
+			Permute().
+			CountingOption("verbose", &wantTwo).Short("v").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 2, wantTwo)
+			assert.Equal(t, []string{"-"}, ret)
+		}
+	}
+
+	{
+		stayFalse := false
+		args := []string{
+			"file1",
+			"--stay-false",
+			"--unknown",
+		}
+		ret, err := NewOpts().
+			Permute().
+			SimpleOption("stay-false", &stayFalse).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "unknown not recognized")
+		}
+		assert.False(t, stayFalse)
+		assert.Equal(t, args, ret)
+	}
+}
+
+func TestRequireOrder(t *testing.T) {
+	wantTrue := false
+	stayFalse := false
+	args := []string{
+		"--want-true",
+		"file1",
+		"--stay-false",
+	}
+	ret, err := NewOpts().
+		Permute().
+		RequireOrder().
+		SimpleOption("want-true", &wantTrue).
+		SimpleOption("stay-false", &stayFalse).
+		ProcessArgs(args)
+	if assert.Nil(t, err) {
+		assert.True(t, wantTrue)
+		assert.False(t, stayFalse)
+		assert.Equal(t, []string{"file1", "--stay-false"}, ret)
+	}
+}
+
+func TestPermuteCommand(t *testing.T) {
+	wantTrue := false
+	wantForce := false
+	ret, err := NewOpts().
+		Permute().
+		SimpleOption("verbose", &wantTrue).
+		Command("deploy", NewOpts().
+			Permute().
+			SimpleOption("force", &wantForce),
+			nil).
+		ProcessArgs([]string{"--verbose", "deploy", "file1", "--force", "file2"})
+	if assert.Nil(t, err) {
+		assert.True(t, wantTrue)
+		assert.True(t, wantForce)
+		assert.Equal(t, []string{"file1", "file2"}, ret)
+	}
+}
@@ -0,0 +1,66 @@
+package opts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Require the preceding option to be given, either on the command line or
+// from the environment.  ProcessArgs() fails if it is not, and lists every
+// missing option.
+func (oc *Opts) Required() *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("required has no option to follow"))
+		return oc
+	}
+	oc.last.minCount = 1
+	return oc
+}
+
+// Require the preceding array, map or counting option to be given at least
+// n times.  Like Required(), but for more than one value.
+func (oc *Opts) AtLeast(n int) *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("at least %d has no option to follow", n))
+		return oc
+	}
+	_, counting := oc.last.handler.(optCountingHandler)
+	if !counting && !isArray(oc.last.handler) {
+		oc.setError(fmt.Errorf("option %s takes one value, use Required()", oc.last.name))
+		return oc
+	}
+	if n < 1 {
+		oc.setError(fmt.Errorf("option %s needs a positive count", oc.last.name))
+		return oc
+	}
+	oc.last.minCount = n
+	return oc
+}
+
+// Check that every option in chain has its minimum number of values in
+// pending.
+func checkRequired(chain []*Opts, pending []optPending) error {
+	counts := make(map[*optInfo]int)
+	for _, p := range pending {
+		counts[p.info]++
+	}
+
+	var missing []string
+	for _, oc := range chain {
+		for _, info := range oc.options() {
+			count := counts[info]
+			if count >= info.minCount {
+				continue
+			}
+			if info.minCount == 1 {
+				missing = append(missing, "--"+info.name)
+			} else {
+				missing = append(missing, fmt.Sprintf("--%s (at least %d, got %d)", info.name, info.minCount, count))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required options: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
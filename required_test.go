@@ -0,0 +1,162 @@
+package opts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequired(t *testing.T) {
+	{
+		wantEleven := 7
+		wantChaos := "calm"
+		ret, err := NewOpts().
+			IntOption("length", &wantEleven).Required().
+			StringOption("name", &wantChaos).Required().
+			ProcessArgs([]string{"--length=11", "--name", "chaos", "left"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, "chaos", wantChaos)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	// Every missing option is listed, and nothing is stored.
+	{
+		stayEleven := 7
+		stayCalm := "calm"
+		stayFalse := false
+		args := []string{
+			"--verbose",
+			"left",
+		}
+		ret, err := NewOpts().
+			IntOption("length", &stayEleven).Required().
+			StringOption("name", &stayCalm).Required().
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "missing required options: --length, --name")
+		}
+		assert.Equal(t, 7, stayEleven)
+		assert.Equal(t, "calm", stayCalm)
+		assert.False(t, stayFalse)
+		assert.Equal(t, args, ret)
+	}
+
+	// Either form of a negatable option counts.
+	{
+		wantFalse := true
+		_, err := NewOpts().
+			NegatableOption("verbose", &wantFalse).Required().
+			ProcessArgs([]string{"--noverbose"})
+		if assert.Nil(t, err) {
+			assert.False(t, wantFalse)
+		}
+	}
+
+	// So does the environment.
+	{
+		t.Setenv("TEST_OPTS_PORT", "8080")
+		wantPort := 80
+		_, err := NewOpts().
+			IntOption("port", &wantPort).Env("TEST_OPTS_PORT").Required().
+			ProcessArgs([]string{})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 8080, wantPort)
+		}
+	}
+
+	{
+		_, err := NewOpts().
+			Required().
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no option")
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	{
+		wantTwo := []string{}
+		wantLabels := map[string]string{}
+		_, err := NewOpts().
+			StringArrayOption("file", &wantTwo).AtLeast(2).
+			StringMapOption("label", &wantLabels).Required().
+			ProcessArgs([]string{"--file", "a", "--file", "b", "--label", "app=web"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"a", "b"}, wantTwo)
+			assert.Equal(t, map[string]string{"app": "web"}, wantLabels)
+		}
+	}
+
+	{
+		stayEmpty := []string{}
+		stayZero := 0
+		stayLabels := map[string]string{}
+		_, err := NewOpts().
+			StringArrayOption("file", &stayEmpty).AtLeast(2).
+			CountingOption("verbose", &stayZero).AtLeast(3).
+			StringMapOption("label", &stayLabels).Required().
+			ProcessArgs([]string{"--file", "a", "--verbose"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(),
+				"missing required options: --file (at least 2, got 1), --label, --verbose (at least 3, got 1)")
+		}
+		assert.Empty(t, stayEmpty)
+		assert.Equal(t, 0, stayZero)
+		assert.Empty(t, stayLabels)
+	}
+
+	{
+		length := 7
+		_, err := NewOpts().
+			IntOption("length", &length).AtLeast(2).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "use Required()")
+		}
+	}
+
+	{
+		files := []string{}
+		_, err := NewOpts().
+			StringArrayOption("file", &files).AtLeast(0).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "positive count")
+		}
+	}
+}
+
+func TestRequiredCommand(t *testing.T) {
+	wantTrue := false
+	wantChaos := "calm"
+	oc := NewOpts().
+		SimpleOption("verbose", &wantTrue).Required().Persistent().
+		Command("deploy", NewOpts().StringOption("target", &wantChaos).Required(), nil)
+
+	_, err := oc.ProcessArgs([]string{"deploy", "--verbose"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "missing required options: --target")
+	}
+	assert.False(t, wantTrue)
+}
+
+func TestRequiredUsage(t *testing.T) {
+	length := 0
+	files := []string{}
+	var b strings.Builder
+	err := NewOpts().
+		IntOption("length", &length).Required().
+		StringArrayOption("file", &files).AtLeast(2).
+		Usage(&b)
+	require.Nil(t, err)
+	assert.Equal(t, ""+
+		"  --file=STRING  (repeatable) (at least 2)\n"+
+		"  --length=INT   (required)\n",
+		b.String())
+}
@@ -0,0 +1,88 @@
+package opts
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Expand response files in the arguments, like gcc's @file.  An argument
+// @path is replaced by the words in the file path, read from fsys, which is
+// usually os.DirFS(".").  The file is split into words like a shell would,
+// see below, so it can have one argument per line, or several per line with
+// quoting.  Response files can refer to other response files, which are also
+// read from fsys, but not to themselves.  A bare @ is left alone, and
+// nothing after -- is expanded.
+//
+// Files are split on spaces, tabs and newlines.  Single and double quotes
+// and backslashes work as in a POSIX shell, without any expansions, and #
+// starts a comment.
+//
+// Expansion happens before anything else in ProcessArgs(), so the returned
+// arguments and the Index of errors refer to the expanded arguments.
+func (oc *Opts) ResponseFiles(fsys fs.FS) *Opts {
+	if fsys == nil {
+		oc.setError(fmt.Errorf("response files need a file system"))
+		return oc
+	}
+	oc.responseFS = fsys
+	return oc
+}
+
+// Expands response files for ResponseFiles().
+type responseExpander struct {
+	fsys fs.FS
+
+	// The files being expanded, innermost last, for finding cycles.
+	open []string
+
+	// Set after --, when nothing more is expanded.
+	done bool
+}
+
+func (re *responseExpander) expand(args []string) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		name, ok := strings.CutPrefix(arg, "@")
+		if re.done || !ok || name == "" {
+			if arg == "--" {
+				re.done = true
+			}
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		name = path.Clean(name)
+		if slices.Contains(re.open, name) {
+			return nil, fmt.Errorf("response file %s includes itself", name)
+		}
+		data, err := fs.ReadFile(re.fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("response file: %w", err)
+		}
+		words, err := splitWords(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("response file %s: %w", name, err)
+		}
+
+		re.open = append(re.open, name)
+		words, err = re.expand(words)
+		re.open = re.open[:len(re.open)-1]
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, words...)
+	}
+	return expanded, nil
+}
+
+// Returns args with response files expanded, if enabled by ResponseFiles().
+func (oc *Opts) expandArgs(args []string) ([]string, error) {
+	if oc.responseFS == nil {
+		return args, nil
+	}
+	re := &responseExpander{fsys: oc.responseFS}
+	return re.expand(args)
+}
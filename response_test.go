@@ -0,0 +1,100 @@
+package opts
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"build.rsp":        {Data: []byte("--length 11\n--name 'two words'\n@common/flags.rsp\n")},
+		"common/flags.rsp": {Data: []byte("# Shared flags.\n--verbose\n")},
+		"loop.rsp":         {Data: []byte("--verbose @loop2.rsp\n")},
+		"loop2.rsp":        {Data: []byte("@./loop.rsp\n")},
+		"bad.rsp":          {Data: []byte("--name 'oops\n")},
+	}
+
+	{
+		wantTrue := false
+		wantEleven := 7
+		wantWords := "calm"
+		ret, err := NewOpts().
+			ResponseFiles(fsys).
+			SimpleOption("verbose", &wantTrue).
+			IntOption("length", &wantEleven).
+			StringOption("name", &wantWords).
+			ProcessArgs([]string{"@build.rsp", "left", "@", "--", "@build.rsp"})
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, "two words", wantWords)
+			assert.Equal(t, []string{"left", "@", "--", "@build.rsp"}, ret)
+		}
+	}
+
+	// Later arguments override the file.
+	{
+		wantTwelve := 7
+		_, err := NewOpts().
+			ResponseFiles(fsys).
+			SimpleOption("verbose", new(bool)).
+			IntOption("length", &wantTwelve).
+			StringOption("name", new(string)).
+			ProcessArgs([]string{"@build.rsp", "--length=12"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 12, wantTwelve)
+		}
+	}
+
+	// Without ResponseFiles(), @ is not special.
+	{
+		ret, err := NewOpts().
+			ProcessArgs([]string{"@build.rsp"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"@build.rsp"}, ret)
+		}
+	}
+
+	{
+		stayFalse := false
+		args := []string{"@loop.rsp"}
+		ret, err := NewOpts().
+			ResponseFiles(fsys).
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "response file loop.rsp includes itself")
+		}
+		assert.False(t, stayFalse)
+		assert.Equal(t, args, ret)
+	}
+
+	{
+		_, err := NewOpts().
+			ResponseFiles(fsys).
+			ProcessArgs([]string{"@missing.rsp"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "missing.rsp")
+		}
+	}
+
+	{
+		_, err := NewOpts().
+			ResponseFiles(fsys).
+			ProcessArgs([]string{"@bad.rsp"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "response file bad.rsp: unterminated single quote")
+		}
+	}
+
+	{
+		_, err := NewOpts().
+			ResponseFiles(nil).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "need a file system")
+		}
+	}
+}
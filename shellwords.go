@@ -0,0 +1,78 @@
+package opts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Split text into words the way a POSIX shell would, without any expansions.
+// Words are separated by unquoted spaces, tabs and newlines.  Single quotes
+// keep everything up to the next single quote, double quotes keep everything
+// up to the next double quote except that backslash escapes $ ` " \ and
+// newline, and an unquoted backslash escapes the next character.  Backslash
+// newline joins lines.  An unquoted # at the start of a word starts a comment
+// which runs to the end of the line.
+func splitWords(text string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	// Quoted empty strings are words, so track this separately from
+	// word.Len().
+	inWord := false
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '#' && !inWord:
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '\\':
+			if i+1 == len(text) {
+				return nil, fmt.Errorf("unterminated backslash at offset %d", i)
+			}
+			i++
+			if text[i] != '\n' {
+				word.WriteByte(text[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := strings.IndexByte(text[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote at offset %d", i)
+			}
+			word.WriteString(text[i+1 : i+1+end])
+			inWord = true
+			i += 1 + end
+		case c == '"':
+			start := i
+			for i++; ; i++ {
+				if i == len(text) {
+					return nil, fmt.Errorf("unterminated double quote at offset %d", start)
+				}
+				if text[i] == '"' {
+					break
+				}
+				if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("$`\"\\\n", text[i+1]) >= 0 {
+					i++
+					if text[i] != '\n' {
+						word.WriteByte(text[i])
+					}
+					continue
+				}
+				word.WriteByte(text[i])
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
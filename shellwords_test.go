@@ -0,0 +1,65 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitWords(t *testing.T) {
+	{
+		words, err := splitWords("  one\ttwo\nthree  ")
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"one", "two", "three"}, words)
+		}
+	}
+
+	{
+		words, err := splitWords(`'single $quoted' "double \"quoted\" \$x \n" back\ slash`)
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"single $quoted", `double "quoted" $x \n`, "back slash"}, words)
+		}
+	}
+
+	{
+		words, err := splitWords("'' a''b \"\"c --name=\"x y\"")
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"", "ab", "c", "--name=x y"}, words)
+		}
+	}
+
+	{
+		words, err := splitWords("one \\\ntwo# not a comment\n# comment 'x\nthree")
+		if assert.Nil(t, err) {
+			assert.Equal(t, []string{"one", "two#", "not", "a", "comment", "three"}, words)
+		}
+	}
+
+	{
+		words, err := splitWords("")
+		if assert.Nil(t, err) {
+			assert.Empty(t, words)
+		}
+	}
+
+	{
+		_, err := splitWords("one 'two")
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "unterminated single quote at offset 4")
+		}
+	}
+
+	{
+		_, err := splitWords(`one "two \"`)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "unterminated double quote at offset 4")
+		}
+	}
+
+	{
+		_, err := splitWords(`one\`)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "unterminated backslash")
+		}
+	}
+}
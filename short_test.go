@@ -0,0 +1,322 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortOption(t *testing.T) {
+	{
+		wantTrue := false
+		stayFalse := false
+		args := []string{
+			"-t",
+			"left",
+		}
+		ret, err := NewOpts().
+			SimpleOption("want-true", &wantTrue).Short("t").
+			SimpleOption("stay-false", &stayFalse).Short("f").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.False(t, stayFalse)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantEleven := 7
+		args := []string{
+			"-e", "11",
+			"left",
+		}
+		ret, err := NewOpts().
+			IntOption("want-eleven", &wantEleven).Short("e").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantEleven := 7
+		args := []string{
+			"-e11",
+			"left",
+		}
+		ret, err := NewOpts().
+			IntOption("want-eleven", &wantEleven).Short("e").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantEleven := 7
+		args := []string{
+			"-e",
+		}
+		ret, err := NewOpts().
+			IntOption("want-eleven", &wantEleven).Short("e").
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "-e missing")
+		}
+		assert.Equal(t, 7, wantEleven)
+		assert.Equal(t, []string{"-e"}, ret)
+	}
+
+	{
+		wantFalse := true
+		args := []string{
+			"-f",
+			"left",
+		}
+		ret, err := NewOpts().
+			NegatableOption("want-false", &wantFalse).Short("f").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.True(t, wantFalse)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	// A lone - is an argument, not an option.
+	{
+		wantTrue := false
+		args := []string{
+			"-t",
+			"-",
+			"left",
+		}
+		ret, err := NewOpts().
+			SimpleOption("want-true", &wantTrue).Short("t").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.Equal(t, []string{"-", "left"}, ret)
+		}
+	}
+}
+
+func TestShortBundle(t *testing.T) {
+	{
+		wantThree := 0
+		wantTrue := false
+		args := []string{
+			"-vvxv",
+			"left",
+		}
+		ret, err := NewOpts().
+			CountingOption("verbose", &wantThree).Short("v").
+			SimpleOption("extra", &wantTrue).Short("x").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 3, wantThree)
+			assert.True(t, wantTrue)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantTwo := 0
+		wantLength := 7
+		args := []string{
+			"-vvl24",
+			"left",
+		}
+		ret, err := NewOpts().
+			CountingOption("verbose", &wantTwo).Short("v").
+			IntOption("length", &wantLength).Short("l").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 2, wantTwo)
+			assert.Equal(t, 24, wantLength)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		wantTwo := 0
+		wantLength := 7
+		args := []string{
+			"-vvl", "24",
+			"left",
+		}
+		ret, err := NewOpts().
+			CountingOption("verbose", &wantTwo).Short("v").
+			IntOption("length", &wantLength).Short("l").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 2, wantTwo)
+			assert.Equal(t, 24, wantLength)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	{
+		stayZero := 0
+		args := []string{
+			"-vqv",
+			"left",
+		}
+		ret, err := NewOpts().
+			CountingOption("verbose", &stayZero).Short("v").
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "-q not recognized")
+		}
+		assert.Equal(t, 0, stayZero)
+		assert.Equal(t, []string{"-vqv", "left"}, ret)
+	}
+
+	{
+		wantLength := 7
+		args := []string{
+			"-ltwelve",
+		}
+		ret, err := NewOpts().
+			IntOption("length", &wantLength).Short("l").
+			ProcessArgs(args)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "invalid syntax")
+		}
+		assert.Equal(t, 7, wantLength)
+		assert.Equal(t, []string{"-ltwelve"}, ret)
+	}
+}
+
+func TestShortMixed(t *testing.T) {
+	{
+		wantThree := 0
+		wantLength := 7
+		wantChaos := "calm"
+		args := []string{
+			"-v",
+			"--verbose",
+			"--length=24",
+			"-vs", "chaos",
+			"left",
+		}
+		ret, err := NewOpts().
+			CountingOption("verbose", &wantThree).Short("v").
+			IntOption("length", &wantLength).Short("l").
+			StringOption("string", &wantChaos).Short("s").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 3, wantThree)
+			assert.Equal(t, 24, wantLength)
+			assert.Equal(t, "chaos", wantChaos)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	// Optional options do not take a following short option as their
+	// value.
+	{
+		wantEleven := 7
+		wantTrue := false
+		args := []string{
+			"--want-eleven", "-t",
+			"left",
+		}
+		ret, err := NewOpts().
+			OptionalIntOption("want-eleven", &wantEleven, 11).
+			SimpleOption("want-true", &wantTrue).Short("t").
+			ProcessArgs(args)
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, wantEleven)
+			assert.True(t, wantTrue)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+}
+
+func TestShortErrors(t *testing.T) {
+	{
+		wantTrue := false
+		_, err := NewOpts().
+			Short("t").
+			SimpleOption("want-true", &wantTrue).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no option")
+		}
+	}
+
+	{
+		wantTrue := false
+		otherTrue := false
+		_, err := NewOpts().
+			SimpleOption("want-true", &wantTrue).Short("t").
+			SimpleOption("other-true", &otherTrue).Short("t").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "already exists")
+		}
+	}
+
+	{
+		wantTrue := false
+		_, err := NewOpts().
+			SimpleOption("want-true", &wantTrue).Short("tt").
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "single character")
+		}
+	}
+}
+
+// Without any short options, arguments like -1 are not options.
+func TestNoShortOptions(t *testing.T) {
+	{
+		stayZero := 0
+		ret, err := NewOpts().
+			IntOption("n", &stayZero).
+			ProcessArgs([]string{"-1", "x"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 0, stayZero)
+			assert.Equal(t, []string{"-1", "x"}, ret)
+		}
+	}
+
+	{
+		wantTwo := 0
+		ret, err := NewOpts().
+			IntOption("n", &wantTwo).
+			Permute().
+			ProcessArgs([]string{"-1", "--n=2", "-x"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 2, wantTwo)
+			assert.Equal(t, []string{"-1", "-x"}, ret)
+		}
+	}
+
+	// Persistent short options still apply to subcommands.
+	{
+		wantOne := 0
+		ret, err := NewOpts().
+			CountingOption("verbose", &wantOne).Short("v").Persistent().
+			Command("deploy", NewOpts(), nil).
+			ProcessArgs([]string{"deploy", "-v", "x"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 1, wantOne)
+			assert.Equal(t, []string{"x"}, ret)
+		}
+	}
+
+	// With short options, unknown ones are still errors.
+	{
+		stayFalse := false
+		_, err := NewOpts().
+			SimpleOption("verbose", &stayFalse).Short("v").
+			ProcessArgs([]string{"-1"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "arg -1 not recognized")
+		}
+	}
+}
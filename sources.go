@@ -0,0 +1,171 @@
+package opts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// Source is a layer of option values beneath the command line, see
+// Sources().
+type Source struct {
+	// Describes the source, for errors.
+	name string
+
+	// Returns the values for oc and its subcommands, or nil to skip the
+	// source.
+	load func(oc *Opts) (map[*Opts][]optPending, error)
+}
+
+// A config file read from fsys, like ConfigFile("etc/tool.json", ...).
+// It is an error for the file to be missing.  See LoadConfig() for the
+// format.
+func ConfigFile(fsys fs.FS, name string, format ConfigFormat) Source {
+	return configFileSource(fsys, name, format, false)
+}
+
+// Like ConfigFile(), but a missing file is skipped, for config files which
+// the user may not have created.
+func OptionalConfigFile(fsys fs.FS, name string, format ConfigFormat) Source {
+	return configFileSource(fsys, name, format, true)
+}
+
+func configFileSource(fsys fs.FS, name string, format ConfigFormat, optional bool) Source {
+	return Source{name, func(oc *Opts) (map[*Opts][]optPending, error) {
+		f, err := fsys.Open(name)
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return oc.readConfig(f, format, name)
+	}}
+}
+
+// A config file read from r, see LoadConfig() for the format.  r is read
+// by the first ProcessArgs(), and its contents are kept for later ones.
+func ConfigReader(r io.Reader, format ConfigFormat) Source {
+	read := sync.OnceValues(func() ([]byte, error) {
+		return io.ReadAll(r)
+	})
+	return Source{format.String() + " config", func(oc *Opts) (map[*Opts][]optPending, error) {
+		data, err := read()
+		if err != nil {
+			return nil, err
+		}
+		return oc.readConfig(bytes.NewReader(data), format, "")
+	}}
+}
+
+// Add layers of option values from sources, like config files, which are
+// read by ProcessArgs().  Each source overrides the ones before it, so the
+// precedence from lowest to highest is:
+//
+//   - the values of the option pointers, which are the defaults
+//   - sources, in order
+//   - config files from LoadConfig()
+//   - the environment, see Env() and EnvPrefix()
+//   - the command line
+//
+// An option takes its values from the highest layer which has any.  For
+// array options this means that the values from lower layers are replaced,
+// unless AppendLayers() is used.  Required() and groups of options only see
+// the values which are kept.  If any layer has an error, nothing is stored.
+func (oc *Opts) Sources(sources ...Source) *Opts {
+	oc.sources = append(oc.sources, sources...)
+	return oc
+}
+
+// Keep the values of the preceding array option from every layer, lowest
+// first, rather than taking the values from the highest layer which has
+// any.  See Sources().
+func (oc *Opts) AppendLayers() *Opts {
+	if oc.last == nil {
+		oc.setError(fmt.Errorf("append layers has no option to follow"))
+		return oc
+	}
+	if !isArray(oc.last.handler) {
+		oc.setError(fmt.Errorf("option %s is not an array, only arrays can append layers", oc.last.name))
+		return oc
+	}
+	oc.last.appendLayers = true
+	return oc
+}
+
+// Returns the committers from every layer for the Opts used to parse the
+// command line, in the order they should be committed.  See Sources().
+func mergeLayers(ps *optParse) ([]optPending, error) {
+	chain := ps.chain
+	var layers []map[*Opts][]optPending
+	for _, oc := range chain {
+		for _, s := range oc.sources {
+			layer, err := s.load(oc)
+			if err != nil {
+				return nil, fmt.Errorf("source %s: %w", s.name, err)
+			}
+			layers = append(layers, layer)
+		}
+	}
+
+	config := make(map[*Opts][]optPending)
+	for _, oc := range chain {
+		config[oc] = oc.loaded
+	}
+	env, err := envLayer(ps)
+	if err != nil {
+		return nil, err
+	}
+	layers = append(layers, config, env, ps.queued)
+
+	// The highest layer with values for each option.
+	top := make(map[*optInfo]int)
+	for i, layer := range layers {
+		for _, oc := range chain {
+			for _, p := range layer[oc] {
+				top[p.info] = i
+			}
+		}
+	}
+
+	// An option of an exclusive group overrides the other options of the
+	// group from lower layers, so --yaml on the command line wins over
+	// json in a config file.
+	dropped := make(map[*optInfo]bool)
+	for _, oc := range chain {
+		for _, g := range oc.groups {
+			if g.kind != optExclusive {
+				continue
+			}
+			highest := -1
+			for _, info := range g.infos {
+				if i, ok := top[info]; ok && i > highest {
+					highest = i
+				}
+			}
+			for _, info := range g.infos {
+				if i, ok := top[info]; ok && i < highest {
+					dropped[info] = true
+				}
+			}
+		}
+	}
+
+	var pending []optPending
+	for i, layer := range layers {
+		for _, oc := range chain {
+			for _, p := range layer[oc] {
+				if dropped[p.info] {
+					continue
+				}
+				if top[p.info] == i || p.info.appendLayers {
+					pending = append(pending, p)
+				}
+			}
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,130 @@
+package opts
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSources(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/tool.json": {Data: []byte(`{"length": 11, "name": "system", "file": ["sys"], "tag": ["sys"]}`)},
+		"home/tool.ini": {Data: []byte("name = user\nfile = user\ntag = user\n")},
+		"bad.json":      {Data: []byte(`{"length": "eleven"}`)},
+	}
+
+	{
+		t.Setenv("TEST_OPTS_TAG", "env")
+		wantEleven := 7
+		wantEnv := "calm"
+		wantUser := []string{}
+		wantTags := []string{}
+		ret, err := NewOpts().
+			Sources(
+				ConfigFile(fsys, "etc/tool.json", ConfigJSON),
+				OptionalConfigFile(fsys, "home/missing.yaml", ConfigYAML),
+				OptionalConfigFile(fsys, "home/tool.ini", ConfigINI),
+			).
+			IntOption("length", &wantEleven).
+			StringOption("name", &wantEnv).Env("TEST_OPTS_NAME").
+			StringArrayOption("file", &wantUser).
+			StringArrayOption("tag", &wantTags).Env("TEST_OPTS_TAG").AppendLayers().
+			ProcessArgs([]string{"--tag=args", "left"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 11, wantEleven)
+			assert.Equal(t, "user", wantEnv)
+			assert.Equal(t, []string{"user"}, wantUser)
+			assert.Equal(t, []string{"sys", "user", "env", "args"}, wantTags)
+			assert.Equal(t, []string{"left"}, ret)
+		}
+	}
+
+	// Sources sit beneath LoadConfig(), the environment and the command
+	// line.
+	{
+		t.Setenv("TEST_OPTS_NAME", "env")
+		wantTwelve := 7
+		wantEnv := "calm"
+		wantArgs := []string{}
+		oc := NewOpts().
+			Sources(ConfigFile(fsys, "etc/tool.json", ConfigJSON)).
+			IntOption("length", &wantTwelve).
+			StringOption("name", &wantEnv).Env("TEST_OPTS_NAME").
+			StringArrayOption("file", &wantArgs).
+			StringArrayOption("tag", new([]string))
+		assert.Nil(t, oc.LoadConfig(strings.NewReader("length: 12"), ConfigYAML))
+		_, err := oc.ProcessArgs([]string{"--file=args"})
+		if assert.Nil(t, err) {
+			assert.Equal(t, 12, wantTwelve)
+			assert.Equal(t, "env", wantEnv)
+			assert.Equal(t, []string{"args"}, wantArgs)
+		}
+	}
+
+	// Subcommands take their part of the file.
+	{
+		wantTrue := false
+		wantThree := 1
+		_, err := NewOpts().
+			Sources(ConfigReader(strings.NewReader(`{"verbose": true, "deploy": {"replicas": 3}}`), ConfigJSON)).
+			SimpleOption("verbose", &wantTrue).
+			Command("deploy", NewOpts().IntOption("replicas", &wantThree), nil).
+			ProcessArgs([]string{"deploy"})
+		if assert.Nil(t, err) {
+			assert.True(t, wantTrue)
+			assert.Equal(t, 3, wantThree)
+		}
+	}
+}
+
+func TestSourcesErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"good.json": {Data: []byte(`{"name": "good"}`)},
+		"bad.json":  {Data: []byte(`{"length": "eleven"}`)},
+	}
+
+	// A bad layer stores nothing, from any layer.
+	{
+		stayCalm := "calm"
+		stayEleven := 11
+		stayFalse := false
+		_, err := NewOpts().
+			Sources(
+				ConfigFile(fsys, "good.json", ConfigJSON),
+				ConfigFile(fsys, "bad.json", ConfigJSON),
+			).
+			StringOption("name", &stayCalm).
+			IntOption("length", &stayEleven).
+			SimpleOption("verbose", &stayFalse).
+			ProcessArgs([]string{"--verbose"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "source bad.json: config length: strconv.Atoi")
+		}
+		assert.Equal(t, "calm", stayCalm)
+		assert.Equal(t, 11, stayEleven)
+		assert.False(t, stayFalse)
+	}
+
+	{
+		stayCalm := "calm"
+		_, err := NewOpts().
+			Sources(ConfigFile(fsys, "missing.json", ConfigJSON)).
+			StringOption("name", &stayCalm).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "source missing.json")
+		}
+	}
+
+	{
+		name := ""
+		_, err := NewOpts().
+			StringOption("name", &name).AppendLayers().
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "only arrays can append layers")
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package opts
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// Names this close to an unknown option are suggested, unless changed
+	// with SuggestDistance().
+	defaultSuggestDistance = 2
+
+	// At most this many names are suggested.
+	maxSuggestions = 3
+)
+
+// Suggest long option names within distance edits of an unknown option, in
+// the error from ProcessArgs().  An edit inserts, deletes or changes a
+// character, or swaps two adjacent characters, so --lenght is one edit from
+// --length.  The default distance is 2, and 0 turns suggestions off.
+func (oc *Opts) SuggestDistance(distance int) *Opts {
+	if distance < 0 {
+		oc.setError(fmt.Errorf("suggest distance %d cannot be negative", distance))
+		return oc
+	}
+	oc.suggestDistance = distance
+	return oc
+}
+
+// Returns the edit distance between a and b, counting swaps of adjacent
+// characters as one edit.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// d[i][j] is the distance between s[:i] and t[:j].
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// Returns the long flags closest to name, nearest first.
+func (oc *Opts) suggest(name string) []string {
+	if oc.suggestDistance == 0 {
+		return nil
+	}
+
+	type candidate struct {
+		flag     string
+		distance int
+	}
+	var candidates []candidate
+	for full := range oc.visibleNames() {
+		if d := editDistance(name, full); d <= oc.suggestDistance {
+			candidates = append(candidates, candidate{"--" + full, d})
+		}
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.flag, b.flag)
+	})
+
+	var flags []string
+	for _, c := range candidates[:min(len(candidates), maxSuggestions)] {
+		flags = append(flags, c.flag)
+	}
+	return flags
+}
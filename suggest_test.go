@@ -0,0 +1,99 @@
+package opts
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggest(t *testing.T) {
+	{
+		length := 7
+		verbose := false
+		_, err := NewOpts().
+			IntOption("length", &length).
+			SimpleOption("verbose", &verbose).
+			ProcessArgs([]string{"--lenght=11"})
+		var uerr *UnknownOptionError
+		if assert.True(t, errors.As(err, &uerr)) {
+			assert.Equal(t, []string{"--length"}, uerr.Suggestions)
+			assert.Equal(t, "arg lenght not recognized, did you mean --length?", err.Error())
+		}
+	}
+
+	// Negated forms and aliases are suggested, nearest first, and only a
+	// few of them.
+	{
+		color := false
+		colour := false
+		cols := 0
+		cool := false
+		_, err := NewOpts().
+			NegatableOption("color", &color).
+			SimpleOption("colour", &colour).
+			IntOption("cols", &cols).AKA("col").
+			SimpleOption("cool", &cool).
+			ProcessArgs([]string{"--colr"})
+		var uerr *UnknownOptionError
+		if assert.True(t, errors.As(err, &uerr)) {
+			assert.Equal(t, []string{"--col", "--color", "--cols"}, uerr.Suggestions)
+			assert.Contains(t, err.Error(), "did you mean --col, --color or --cols?")
+		}
+
+		_, err = NewOpts().
+			NegatableOption("color", &color).
+			ProcessArgs([]string{"--nocolr"})
+		if assert.True(t, errors.As(err, &uerr)) {
+			assert.Equal(t, []string{"--nocolor"}, uerr.Suggestions)
+		}
+	}
+
+	// Persistent options are suggested after subcommands.
+	{
+		verbose := false
+		force := false
+		_, err := NewOpts().
+			SimpleOption("verbose", &verbose).Persistent().
+			Command("deploy", NewOpts().SimpleOption("force", &force), nil).
+			ProcessArgs([]string{"deploy", "--verbsoe"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "did you mean --verbose?")
+		}
+	}
+
+	{
+		length := 7
+		_, err := NewOpts().
+			IntOption("length", &length).
+			SuggestDistance(0).
+			ProcessArgs([]string{"--lenght=11"})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "arg lenght not recognized", err.Error())
+		}
+
+		_, err = NewOpts().
+			IntOption("length", &length).
+			SuggestDistance(3).
+			ProcessArgs([]string{"--lngth"})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "did you mean --length?")
+		}
+
+		_, err = NewOpts().
+			SuggestDistance(-1).
+			ProcessArgs([]string{})
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "cannot be negative")
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("length", "length"))
+	assert.Equal(t, 1, editDistance("lenght", "length"))
+	assert.Equal(t, 1, editDistance("lngth", "length"))
+	assert.Equal(t, 1, editDistance("lengths", "length"))
+	assert.Equal(t, 2, editDistance("legnht", "length"))
+	assert.Equal(t, 6, editDistance("", "length"))
+}
@@ -0,0 +1,171 @@
+package opts
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const (
+	// Usage() wraps lines at this width.
+	usageWidth = 80
+
+	// Flags wider than this get their help text on the following line,
+	// rather than pushing every other option's help to the right.
+	usageMaxFlags = 30
+)
+
+// Returns the name for the option's value, like INT in --length=INT.
+func (info *optInfo) valueName() string {
+	if info.metavar != "" {
+		return info.metavar
+	}
+	switch info.handler.getPointer().(type) {
+	case *int, *[]int:
+		return "INT"
+	case *float64, *[]float64:
+		return "FLOAT"
+	case *string, *[]string:
+		return "STRING"
+	case *map[string]int:
+		return "KEY=INT"
+	case *map[string]float64:
+		return "KEY=FLOAT"
+	case *map[string]string:
+		return "KEY=STRING"
+	default:
+		return "VALUE"
+	}
+}
+
+// Returns the flags column for the option, like "-l, --length=INT".
+func (info *optInfo) usageFlags(indentLongs bool) string {
+	flags := make([]string, 0, len(info.shorts)+1+len(info.aliases))
+	for _, ch := range info.shorts {
+		flags = append(flags, "-"+ch)
+	}
+	prefix := "--"
+	if info.negation != nil {
+		prefix = "--[no]"
+	}
+	flags = append(flags, prefix+info.name)
+	for _, alias := range info.aliases {
+		flags = append(flags, prefix+alias)
+	}
+
+	switch info.handler.getType() {
+	case optOptionalArg:
+		flags[len(flags)-1] += "[=" + info.valueName() + "]"
+	case optRequiredArg:
+		flags[len(flags)-1] += "=" + info.valueName()
+	}
+
+	s := strings.Join(flags, ", ")
+	if indentLongs && len(info.shorts) == 0 {
+		s = "    " + s
+	}
+	return s
+}
+
+// Returns the help column for the option, which is the help text followed
+// by notes about repeating, the default value, whether it is required and
+// the environment variable.
+func (info *optInfo) usageHelp(env string) string {
+	parts := make([]string, 0, 5)
+	if info.help != "" {
+		parts = append(parts, info.help)
+	}
+
+	v := reflect.ValueOf(info.handler.getPointer()).Elem()
+	_, counting := info.handler.(optCountingHandler)
+	if counting || isArray(info.handler) {
+		parts = append(parts, "(repeatable)")
+	}
+
+	// The current value of the pointer is the default.  Zero values are
+	// the common case, and not very interesting.
+	if !counting && !v.IsZero() && (!isArray(info.handler) || v.Len() > 0) {
+		if v.Kind() == reflect.String {
+			parts = append(parts, fmt.Sprintf("(default: %q)", v.Interface()))
+		} else {
+			parts = append(parts, fmt.Sprintf("(default: %v)", v.Interface()))
+		}
+	}
+	if info.minCount == 1 {
+		parts = append(parts, "(required)")
+	} else if info.minCount > 1 {
+		parts = append(parts, fmt.Sprintf("(at least %d)", info.minCount))
+	}
+	if env != "" {
+		parts = append(parts, fmt.Sprintf("(env: %s)", env))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Split text into lines no longer than width, breaking at spaces.  Words
+// longer than width get a line to themselves.
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line == "" {
+			line = word
+		} else if len(line)+1+len(word) <= width {
+			line += " " + word
+		} else {
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Usage writes a table of the options to w, sorted by name.  Each option
+// is shown with its short and long flags, whether it takes a value, its
+// help text, whether it can be repeated, its default and its environment
+// variable.  Defaults are read from the option's pointer, so Usage() should
+// be called before ProcessArgs() has a chance to change them.
+func (oc *Opts) Usage(w io.Writer) error {
+	if oc.err != nil {
+		return oc.err
+	}
+
+	type row struct {
+		flags string
+		help  string
+	}
+	infos := oc.options()
+	rows := make([]row, 0, len(infos))
+	flagsWidth := 0
+	for _, info := range infos {
+		r := row{info.usageFlags(len(oc.shorts) > 0), info.usageHelp(oc.envName(info))}
+		if len(r.flags) <= usageMaxFlags {
+			flagsWidth = max(flagsWidth, len(r.flags))
+		}
+		rows = append(rows, r)
+	}
+
+	// Two spaces before flags, two spaces between flags and help.
+	indent := strings.Repeat(" ", 2+flagsWidth+2)
+	var b strings.Builder
+	for _, r := range rows {
+		lines := wrapText(r.help, usageWidth-len(indent))
+		if len(r.flags) > flagsWidth && len(lines) > 0 {
+			fmt.Fprintf(&b, "  %s\n", r.flags)
+		} else if len(lines) > 0 {
+			fmt.Fprintf(&b, "  %-*s  %s\n", flagsWidth, r.flags, lines[0])
+			lines = lines[1:]
+		} else {
+			fmt.Fprintf(&b, "  %s\n", r.flags)
+		}
+		for _, line := range lines {
+			fmt.Fprintf(&b, "%s%s\n", indent, line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
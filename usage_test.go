@@ -0,0 +1,115 @@
+package opts
+
+import (
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	{
+		length := 24
+		verbose := true
+		files := []string{}
+		level := 0
+		name := "file.dat"
+		debug := 0
+		rate := 0.5
+		var b strings.Builder
+		err := NewOpts().
+			IntOption("length", &length).Short("l").AKA("len").
+			Help("Length of the thing to generate, which is a long description that needs wrapping.").
+			NegatableOption("verbose", &verbose).Short("v").Help("Be chatty.").
+			StringArrayOption("files", &files).Help("Files to read.").Metavar("FILE").
+			OptionalIntOption("level", &level, 3).Help("Level.").
+			StringOption("name", &name).
+			CountingOption("debug", &debug).Short("d").
+			FloatOption("a-very-long-option-name-indeed", &rate).Help("Rate.").
+			Usage(&b)
+		require.Nil(t, err)
+		assert.Equal(t, ""+
+			"      --a-very-long-option-name-indeed=FLOAT\n"+
+			"                           Rate. (default: 0.5)\n"+
+			"  -d, --debug              (repeatable)\n"+
+			"      --files=FILE         Files to read. (repeatable)\n"+
+			"  -l, --length, --len=INT  Length of the thing to generate, which is a long\n"+
+			"                           description that needs wrapping. (default: 24)\n"+
+			"      --level[=INT]        Level.\n"+
+			"      --name=STRING        (default: \"file.dat\")\n"+
+			"  -v, --[no]verbose        Be chatty. (default: true)\n",
+			b.String())
+	}
+
+	// Without short options, there is no space reserved for them.
+	{
+		verbose := false
+		names := []string{"a", "b"}
+		var b strings.Builder
+		err := NewOpts().
+			SimpleOption("verbose", &verbose).
+			StringArrayOption("names", &names).
+			Usage(&b)
+		require.Nil(t, err)
+		assert.Equal(t, ""+
+			"  --names=STRING  (repeatable) (default: [a b])\n"+
+			"  --verbose\n",
+			b.String())
+	}
+
+	{
+		port := 80
+		verbose := false
+		var b strings.Builder
+		err := NewOpts().
+			EnvPrefix("APP_").
+			IntOption("port", &port).Env("PORT").
+			SimpleOption("verbose", &verbose).
+			Usage(&b)
+		require.Nil(t, err)
+		assert.Equal(t, ""+
+			"  --port=INT  (default: 80) (env: PORT)\n"+
+			"  --verbose   (env: APP_VERBOSE)\n",
+			b.String())
+	}
+
+	{
+		length := 24
+		var b strings.Builder
+		err := NewOpts().
+			Help("Length.").
+			IntOption("length", &length).
+			Usage(&b)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), "no option")
+		}
+		assert.Empty(t, b.String())
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Empty(t, wrapText("", 10))
+	assert.Equal(t, []string{"one two", "three"}, wrapText("one two three", 8))
+	assert.Equal(t, []string{"a", "longerthanwidth", "b"}, wrapText("a longerthanwidth b", 8))
+}
+
+func ExampleOpts_Usage() {
+	length := 24
+	var verbose bool
+	files := []string{}
+	err := NewOpts().
+		IntOption("length", &length).Short("l").Help("Length of data.").
+		StringArrayOption("files", &files).Metavar("FILE").Help("Input files.").
+		NegatableOption("verbose", &verbose).Short("v").Help("Be chatty.").
+		Usage(os.Stdout)
+	if err != nil {
+		log.Fatal("Error in usage:", err)
+	}
+	// Output:
+	//       --files=FILE   Input files. (repeatable)
+	//   -l, --length=INT   Length of data. (default: 24)
+	//   -v, --[no]verbose  Be chatty.
+}
@@ -0,0 +1,46 @@
+package opts
+
+import (
+	"slices"
+	"strings"
+)
+
+// Returns true if option name, which can be any of its names, was given a
+// value by the last successful ProcessArgs(), from any source, rather than
+// keeping its default.  So --count=0 is set, even though it leaves count at
+// 0.  Returns false if there is no such option.
+func (oc *Opts) IsSet(name string) bool {
+	_, info, ok := oc.lookup(name)
+	return ok && len(oc.stored[info]) > 0
+}
+
+// Call fn for each option which IsSet(), sorted by name, with the text of
+// each value stored, in order.  Boolean options have "true" or "false", so
+// --verbose and --noverbose can be told apart, and counting options have an
+// empty string for each time they were given.  Map options have
+// values like key=value.  See Provenance() for where the values came from.
+func (oc *Opts) Visit(fn func(name string, rawValues []string)) {
+	visit(oc.visibleNames(), oc.stored, fn)
+}
+
+// Call fn for each of the options in names with values in stored, like
+// Visit().
+func visit(names map[string]*optInfo, stored map[*optInfo][]optPending, fn func(name string, rawValues []string)) {
+	infos := make([]*optInfo, 0, len(stored))
+	for name, info := range names {
+		if name == info.name && len(stored[info]) > 0 {
+			infos = append(infos, info)
+		}
+	}
+	slices.SortFunc(infos, func(a, b *optInfo) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	for _, info := range infos {
+		values := make([]string, 0, len(stored[info]))
+		for _, p := range stored[info] {
+			values = append(values, p.value)
+		}
+		fn(info.name, values)
+	}
+}
@@ -0,0 +1,111 @@
+package opts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSet(t *testing.T) {
+	count := 0
+	length := 24
+	verbose := true
+	oc := NewOpts().
+		IntOption("count", &count).
+		IntOption("length", &length).
+		NegatableOption("verbose", &verbose)
+	assert.False(t, oc.IsSet("count"))
+
+	_, err := oc.ProcessArgs([]string{"--count=0", "--noverbose"})
+	require.Nil(t, err)
+	assert.Equal(t, 0, count)
+	assert.True(t, oc.IsSet("count"))
+	assert.False(t, oc.IsSet("length"))
+	assert.True(t, oc.IsSet("verbose"))
+	assert.True(t, oc.IsSet("noverbose"))
+	assert.False(t, oc.IsSet("missing"))
+
+	// Values from the environment and config files count.
+	t.Setenv("TEST_OPTS_LENGTH", "24")
+	oc = NewOpts().
+		IntOption("count", &count).
+		IntOption("length", &length).Env("TEST_OPTS_LENGTH")
+	require.Nil(t, oc.LoadConfig(strings.NewReader(`{"count": 0}`), ConfigJSON))
+	_, err = oc.ProcessArgs([]string{})
+	require.Nil(t, err)
+	assert.True(t, oc.IsSet("count"))
+	assert.True(t, oc.IsSet("length"))
+}
+
+func TestVisit(t *testing.T) {
+	var (
+		verbose = 0
+		length  = 24
+		files   = []string{}
+		labels  = map[string]string{}
+		force   = false
+		name    = "calm"
+	)
+	deploy := NewOpts().
+		SimpleOption("force", &force)
+	oc := NewOpts().
+		CountingOption("verbose", &verbose).Short("v").Persistent().
+		IntOption("length", &length).Short("l").
+		StringArrayOption("file", &files).
+		StringMapOption("label", &labels).
+		StringOption("name", &name).
+		Command("deploy", deploy, nil)
+
+	_, err := oc.ProcessArgs([]string{
+		"-vl11",
+		"--label", "app=web",
+		"--file=a", "--file", "b",
+		"deploy", "--force", "-v",
+	})
+	require.Nil(t, err)
+
+	type visited struct {
+		name   string
+		values []string
+	}
+	var got []visited
+	oc.Visit(func(name string, rawValues []string) {
+		got = append(got, visited{name, rawValues})
+	})
+	assert.Equal(t, []visited{
+		{"file", []string{"a", "b"}},
+		{"label", []string{"app=web"}},
+		{"length", []string{"11"}},
+		{"verbose", []string{"", ""}},
+	}, got)
+
+	got = nil
+	deploy.Visit(func(name string, rawValues []string) {
+		got = append(got, visited{name, rawValues})
+	})
+	assert.Equal(t, []visited{
+		{"force", []string{"true"}},
+		{"verbose", []string{"", ""}},
+	}, got)
+
+	// Negated flags record what they store.
+	{
+		color := true
+		quiet := false
+		oc := NewOpts().
+			NegatableOption("color", &color).
+			SimpleOption("quiet", &quiet).Short("q")
+		_, err := oc.ProcessArgs([]string{"--color", "--nocolor", "-q"})
+		require.Nil(t, err)
+		got = nil
+		oc.Visit(func(name string, rawValues []string) {
+			got = append(got, visited{name, rawValues})
+		})
+		assert.Equal(t, []visited{
+			{"color", []string{"true", "false"}},
+			{"quiet", []string{"true"}},
+		}, got)
+	}
+}